@@ -0,0 +1,325 @@
+// Package bps implements the BPS patch format with source, target and patch CRC32 validation.
+//
+// Patches are applied to ROM contents without any copier header.
+package bps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/alttpo/snes"
+)
+
+var (
+	ErrInvalidHeader  = errors.New("bps: invalid header")
+	ErrTruncated      = errors.New("bps: unexpected end of patch")
+	ErrPatchChecksum  = errors.New("bps: patch checksum mismatch")
+	ErrSourceSize     = errors.New("bps: source size mismatch")
+	ErrSourceChecksum = errors.New("bps: source checksum mismatch")
+	ErrTargetChecksum = errors.New("bps: target checksum mismatch")
+	ErrOutOfBounds    = errors.New("bps: action out of bounds")
+	ErrTargetSize     = errors.New("bps: target size too large")
+)
+
+// MaxTargetSize limits the target ApplyTo allocates, since a few bytes of copy actions can declare any size. It is
+// well above the largest expanded ROMs; callers applying larger patches may raise it:
+var MaxTargetSize uint64 = 0x10000000
+
+var header = []byte("BPS1")
+
+// footerSize is the size of the source, target and patch CRC32s at the end of the patch:
+const footerSize = 12
+
+type ActionKind uint8
+
+const (
+	SourceRead ActionKind = iota
+	TargetRead
+	SourceCopy
+	TargetCopy
+)
+
+var actionKindNames = map[ActionKind]string{
+	SourceRead: "SourceRead",
+	TargetRead: "TargetRead",
+	SourceCopy: "SourceCopy",
+	TargetCopy: "TargetCopy",
+}
+
+func (k ActionKind) String() string {
+	if s, ok := actionKindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("ActionKind(%d)", uint8(k))
+}
+
+type Action struct {
+	Kind   ActionKind
+	Length uint64
+
+	// Data holds the literal bytes of a TargetRead action:
+	Data []byte
+	// RelativeOffset moves the source or target cursor of a SourceCopy or TargetCopy action before copying:
+	RelativeOffset int64
+}
+
+type Patch struct {
+	SourceSize uint64
+	TargetSize uint64
+	Metadata   []byte
+
+	Actions []Action
+
+	SourceCRC32 uint32
+	TargetCRC32 uint32
+}
+
+type decoder struct {
+	b []byte
+}
+
+func (d *decoder) number() (n uint64, err error) {
+	shift := uint64(1)
+	for {
+		if len(d.b) == 0 {
+			return 0, ErrTruncated
+		}
+		x := d.b[0]
+		d.b = d.b[1:]
+
+		n += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return
+		}
+		if shift >= 1<<56 {
+			return 0, fmt.Errorf("bps: number overflow")
+		}
+		shift <<= 7
+		n += shift
+	}
+}
+
+func (d *decoder) bytes(n uint64) (b []byte, err error) {
+	if uint64(len(d.b)) < n {
+		return nil, ErrTruncated
+	}
+	b = make([]byte, n)
+	copy(b, d.b[:n])
+	d.b = d.b[n:]
+	return
+}
+
+// Parse decodes a BPS patch and verifies the patch checksum
+func Parse(b []byte) (p *Patch, err error) {
+	if !bytes.HasPrefix(b, header) {
+		return nil, ErrInvalidHeader
+	}
+	if len(b) < len(header)+footerSize {
+		return nil, ErrTruncated
+	}
+
+	footer := b[len(b)-footerSize:]
+	if crc32.ChecksumIEEE(b[:len(b)-4]) != binary.LittleEndian.Uint32(footer[8:12]) {
+		return nil, ErrPatchChecksum
+	}
+
+	p = &Patch{
+		SourceCRC32: binary.LittleEndian.Uint32(footer[0:4]),
+		TargetCRC32: binary.LittleEndian.Uint32(footer[4:8]),
+	}
+
+	d := &decoder{b: b[len(header) : len(b)-footerSize]}
+	if p.SourceSize, err = d.number(); err != nil {
+		return nil, err
+	}
+	if p.TargetSize, err = d.number(); err != nil {
+		return nil, err
+	}
+	var metadataSize uint64
+	if metadataSize, err = d.number(); err != nil {
+		return nil, err
+	}
+	if p.Metadata, err = d.bytes(metadataSize); err != nil {
+		return nil, err
+	}
+
+	for len(d.b) > 0 {
+		var data uint64
+		if data, err = d.number(); err != nil {
+			return nil, err
+		}
+
+		a := Action{
+			Kind:   ActionKind(data & 3),
+			Length: (data >> 2) + 1,
+		}
+		switch a.Kind {
+		case TargetRead:
+			if a.Data, err = d.bytes(a.Length); err != nil {
+				return nil, err
+			}
+		case SourceCopy, TargetCopy:
+			var offs uint64
+			if offs, err = d.number(); err != nil {
+				return nil, err
+			}
+			a.RelativeOffset = int64(offs >> 1)
+			if offs&1 != 0 {
+				a.RelativeOffset = -a.RelativeOffset
+			}
+		}
+		p.Actions = append(p.Actions, a)
+	}
+
+	return
+}
+
+// ApplyTo returns the target produced from source. source is verified against the patch's size and CRC32 before
+// any target byte is produced, and the target is verified against its CRC32 afterwards. The target size is checked
+// against MaxTargetSize and the total length of the actions before the target is allocated.
+func (p *Patch) ApplyTo(source []byte) (target []byte, err error) {
+	if p.TargetSize > MaxTargetSize {
+		return nil, fmt.Errorf("%w: %d bytes, max %d", ErrTargetSize, p.TargetSize, MaxTargetSize)
+	}
+	var total uint64
+	for i := range p.Actions {
+		if p.Actions[i].Length > p.TargetSize-total {
+			return nil, fmt.Errorf("%w: %v at target offset %x", ErrOutOfBounds, p.Actions[i].Kind, total)
+		}
+		total += p.Actions[i].Length
+	}
+	if total != p.TargetSize {
+		return nil, fmt.Errorf("%w: actions produce %d bytes, want %d", ErrOutOfBounds, total, p.TargetSize)
+	}
+	if uint64(len(source)) != p.SourceSize {
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrSourceSize, len(source), p.SourceSize)
+	}
+	if crc32.ChecksumIEEE(source) != p.SourceCRC32 {
+		return nil, ErrSourceChecksum
+	}
+
+	target = make([]byte, p.TargetSize)
+	var outOffs, sourceRel, targetRel int64
+	for i := range p.Actions {
+		a := &p.Actions[i]
+		length := int64(a.Length)
+		switch a.Kind {
+		case SourceRead:
+			if outOffs+length > int64(len(source)) {
+				return nil, fmt.Errorf("%w: %v at target offset %x", ErrOutOfBounds, a.Kind, outOffs)
+			}
+			copy(target[outOffs:outOffs+length], source[outOffs:])
+		case TargetRead:
+			if int64(len(a.Data)) != length {
+				return nil, fmt.Errorf("%w: %v at target offset %x", ErrOutOfBounds, a.Kind, outOffs)
+			}
+			copy(target[outOffs:], a.Data)
+		case SourceCopy:
+			sourceRel += a.RelativeOffset
+			if sourceRel < 0 || sourceRel+length > int64(len(source)) {
+				return nil, fmt.Errorf("%w: %v at target offset %x", ErrOutOfBounds, a.Kind, outOffs)
+			}
+			copy(target[outOffs:outOffs+length], source[sourceRel:])
+			sourceRel += length
+		case TargetCopy:
+			targetRel += a.RelativeOffset
+			if targetRel < 0 || targetRel >= outOffs {
+				return nil, fmt.Errorf("%w: %v at target offset %x", ErrOutOfBounds, a.Kind, outOffs)
+			}
+			// copy byte by byte since the source range may overlap the bytes being written:
+			for j := int64(0); j < length; j++ {
+				target[outOffs+j] = target[targetRel+j]
+			}
+			targetRel += length
+		}
+		outOffs += length
+	}
+
+	if crc32.ChecksumIEEE(target) != p.TargetCRC32 {
+		return nil, ErrTargetChecksum
+	}
+
+	return
+}
+
+// Apply patches the ROM contents and re-reads its header. The ROM is left untouched if the patch does not apply.
+func (p *Patch) Apply(rom *snes.ROM) (err error) {
+	var out []byte
+	if out, err = p.ApplyTo(rom.Contents); err != nil {
+		return
+	}
+
+	rom.Contents = out
+	if uint64(rom.HeaderOffset)+0x50 <= uint64(len(rom.Contents)) {
+		err = rom.ReadHeader()
+	}
+	return
+}
+
+func appendNumber(b []byte, n uint64) []byte {
+	for {
+		x := byte(n & 0x7F)
+		n >>= 7
+		if n == 0 {
+			return append(b, 0x80|x)
+		}
+		b = append(b, x)
+		n--
+	}
+}
+
+func appendUint32(b []byte, n uint32) []byte {
+	return append(b, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+}
+
+// WriteTo encodes the patch including its CRC32s
+func (p *Patch) WriteTo(w io.Writer) (n int64, err error) {
+	b := append([]byte(nil), header...)
+	b = appendNumber(b, p.SourceSize)
+	b = appendNumber(b, p.TargetSize)
+	b = appendNumber(b, uint64(len(p.Metadata)))
+	b = append(b, p.Metadata...)
+
+	for i := range p.Actions {
+		a := &p.Actions[i]
+		if a.Length == 0 {
+			return 0, fmt.Errorf("bps: empty %v action", a.Kind)
+		}
+		b = appendNumber(b, (a.Length-1)<<2|uint64(a.Kind&3))
+		switch a.Kind {
+		case TargetRead:
+			if uint64(len(a.Data)) != a.Length {
+				return 0, fmt.Errorf("bps: %v data length %d does not match %d", a.Kind, len(a.Data), a.Length)
+			}
+			b = append(b, a.Data...)
+		case SourceCopy, TargetCopy:
+			if a.RelativeOffset < 0 {
+				b = appendNumber(b, uint64(-a.RelativeOffset)<<1|1)
+			} else {
+				b = appendNumber(b, uint64(a.RelativeOffset)<<1)
+			}
+		}
+	}
+
+	b = appendUint32(b, p.SourceCRC32)
+	b = appendUint32(b, p.TargetCRC32)
+	b = appendUint32(b, crc32.ChecksumIEEE(b))
+
+	var m int
+	m, err = w.Write(b)
+	n = int64(m)
+	return
+}
+
+// Bytes encodes the patch
+func (p *Patch) Bytes() ([]byte, error) {
+	b := &bytes.Buffer{}
+	if _, err := p.WriteTo(b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,216 @@
+package bps
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"testing"
+
+	"github.com/alttpo/snes"
+)
+
+func TestPatch_ApplyTo_Actions(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	target := []byte("ABCxyxyxyxEFBC")
+
+	p := &Patch{
+		SourceSize: uint64(len(source)),
+		TargetSize: uint64(len(target)),
+		Actions: []Action{
+			{Kind: SourceRead, Length: 3},
+			{Kind: TargetRead, Length: 2, Data: []byte("xy")},
+			// overlapping copy repeats "xy":
+			{Kind: TargetCopy, Length: 5, RelativeOffset: 3},
+			{Kind: SourceCopy, Length: 2, RelativeOffset: 4},
+			{Kind: SourceCopy, Length: 2, RelativeOffset: -5},
+		},
+		SourceCRC32: crc32.ChecksumIEEE(source),
+		TargetCRC32: crc32.ChecksumIEEE(target),
+	}
+
+	enc, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err = Parse(enc); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := p.ApplyTo(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, target) {
+		t.Errorf("ApplyTo() = %q, want %q", out, target)
+	}
+}
+
+func TestPatch_ApplyTo_TargetSize(t *testing.T) {
+	source := []byte("ABCD")
+	tests := []struct {
+		name       string
+		targetSize uint64
+		actions    []Action
+		want       error
+	}{
+		{"over max", 1 << 40, []Action{{Kind: SourceCopy, Length: 1 << 40}}, ErrTargetSize},
+		{"actions short", 1 << 20, []Action{{Kind: SourceRead, Length: 4}}, ErrOutOfBounds},
+		{"actions long", 4, []Action{{Kind: SourceRead, Length: 4}, {Kind: SourceCopy, Length: 4}}, ErrOutOfBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Patch{
+				SourceSize:  uint64(len(source)),
+				TargetSize:  tt.targetSize,
+				Actions:     tt.actions,
+				SourceCRC32: crc32.ChecksumIEEE(source),
+			}
+			enc, err := p.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p, err = Parse(enc); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = p.ApplyTo(source); !errors.Is(err, tt.want) {
+				t.Errorf("ApplyTo() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatch_ApplyTo_Expanded(t *testing.T) {
+	// a target just over 16MiB filled by repeating the first byte:
+	source := []byte("ABCD")
+	target := bytes.Repeat([]byte("A"), 0x1000001)
+	p := &Patch{
+		SourceSize: uint64(len(source)),
+		TargetSize: uint64(len(target)),
+		Actions: []Action{
+			{Kind: SourceRead, Length: 1},
+			{Kind: TargetCopy, Length: uint64(len(target)) - 1},
+		},
+		SourceCRC32: crc32.ChecksumIEEE(source),
+		TargetCRC32: crc32.ChecksumIEEE(target),
+	}
+	enc, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err = Parse(enc); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := p.ApplyTo(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, target) {
+		t.Error("ApplyTo() differs from target")
+	}
+}
+
+func TestParse_PatchChecksum(t *testing.T) {
+	p, err := Create([]byte("source"), []byte("target"), []byte("meta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err = Parse(enc); err != nil {
+		t.Fatal(err)
+	}
+	if string(p.Metadata) != "meta" {
+		t.Errorf("Metadata = %q, want %q", p.Metadata, "meta")
+	}
+
+	enc[len(header)+4] ^= 0xFF
+	if _, err = Parse(enc); !errors.Is(err, ErrPatchChecksum) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrPatchChecksum)
+	}
+}
+
+func sampleContents(size int, seed int64) []byte {
+	b := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+func TestPatch_Apply_RejectsWrongSource(t *testing.T) {
+	source := sampleContents(0x10000, 1)
+	target := append([]byte(nil), source...)
+	target[0x1234] ^= 0xFF
+
+	p, err := Create(source, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := append([]byte(nil), source...)
+	wrong[0] ^= 0xFF
+	rom, err := snes.NewROM("", wrong)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Apply(rom); !errors.Is(err, ErrSourceChecksum) {
+		t.Fatalf("Apply() error = %v, want %v", err, ErrSourceChecksum)
+	}
+	if !bytes.Equal(rom.Contents, wrong) {
+		t.Fatal("expected ROM contents to be untouched")
+	}
+
+	rom, err = snes.NewROM("", append([]byte(nil), source...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Apply(rom); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rom.Contents, target) {
+		t.Fatal("patched ROM differs from target")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	source := sampleContents(0x400000, 2)
+
+	// expand to 8MiB with relocated and repeated data:
+	target := make([]byte, 0x800000)
+	copy(target, source)
+	copy(target[0x400000:], source[0x100000:0x200000])
+	for i := 0x500000; i < 0x580000; i++ {
+		target[i] = byte(i >> 4)
+	}
+	copy(target[0x600000:], target[0x500000:0x580000])
+	for i := 0; i < 100; i++ {
+		target[i*0x1000] ^= 0xFF
+	}
+
+	p, err := Create(source, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc) > 0x2000 {
+		t.Errorf("len(Bytes()) = %d, expected a small patch", len(enc))
+	}
+
+	if p, err = Parse(enc); err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.ApplyTo(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, target) {
+		t.Fatal("patched source differs from target")
+	}
+}
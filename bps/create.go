@@ -0,0 +1,149 @@
+package bps
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/alttpo/snes"
+)
+
+const (
+	// minMatch is the shortest run worth encoding as anything other than a TargetRead:
+	minMatch = 4
+	// goodMatch is a SourceRead length long enough that searching for a longer copy is not worthwhile:
+	goodMatch = 64
+	hashBits  = 16
+	// maxChain limits how many earlier positions with the same hash are compared:
+	maxChain = 32
+)
+
+// matcher finds the longest earlier occurrence of a sequence in data using hash chains
+type matcher struct {
+	data []byte
+	head []int32
+	prev []int32
+	n    int
+}
+
+func newMatcher(data []byte) *matcher {
+	m := &matcher{
+		data: data,
+		head: make([]int32, 1<<hashBits),
+		prev: make([]int32, len(data)),
+	}
+	for i := range m.head {
+		m.head[i] = -1
+	}
+	return m
+}
+
+func hash(b []byte) uint32 {
+	return (binary.LittleEndian.Uint32(b) * 2654435761) >> (32 - hashBits)
+}
+
+// index makes all positions before n available as match candidates
+func (m *matcher) index(n int) {
+	if n > len(m.data)-minMatch+1 {
+		n = len(m.data) - minMatch + 1
+	}
+	for ; m.n < n; m.n++ {
+		h := hash(m.data[m.n:])
+		m.prev[m.n] = m.head[h]
+		m.head[h] = int32(m.n)
+	}
+}
+
+// find returns the position and length of the longest indexed match for the start of b
+func (m *matcher) find(b []byte) (pos int, length int) {
+	if len(b) < minMatch {
+		return
+	}
+	candidate := m.head[hash(b)]
+	for chain := 0; candidate >= 0 && chain < maxChain; chain++ {
+		c := int(candidate)
+		l := 0
+		for c+l < len(m.data) && l < len(b) && m.data[c+l] == b[l] {
+			l++
+		}
+		if l > length {
+			pos, length = c, l
+		}
+		candidate = m.prev[c]
+	}
+	return
+}
+
+// Create diffs source against target and returns a patch that encodes target as a delta against source
+func Create(source, target, metadata []byte) (p *Patch, err error) {
+	p = &Patch{
+		SourceSize:  uint64(len(source)),
+		TargetSize:  uint64(len(target)),
+		Metadata:    metadata,
+		SourceCRC32: crc32.ChecksumIEEE(source),
+		TargetCRC32: crc32.ChecksumIEEE(target),
+	}
+
+	src := newMatcher(source)
+	src.index(len(source))
+	tgt := newMatcher(target)
+
+	var sourceRel, targetRel int64
+	litStart := 0
+	flush := func(i int) {
+		if litStart < i {
+			p.Actions = append(p.Actions, Action{
+				Kind:   TargetRead,
+				Length: uint64(i - litStart),
+				Data:   append([]byte(nil), target[litStart:i]...),
+			})
+		}
+	}
+
+	for i := 0; i < len(target); {
+		best := Action{Kind: SourceRead}
+		for i+int(best.Length) < len(source) && i+int(best.Length) < len(target) &&
+			source[i+int(best.Length)] == target[i+int(best.Length)] {
+			best.Length++
+		}
+
+		pos := 0
+		if best.Length < goodMatch {
+			if sp, l := src.find(target[i:]); uint64(l) > best.Length {
+				best = Action{Kind: SourceCopy, Length: uint64(l)}
+				pos = sp
+			}
+			tgt.index(i)
+			if tp, l := tgt.find(target[i:]); uint64(l) > best.Length {
+				best = Action{Kind: TargetCopy, Length: uint64(l)}
+				pos = tp
+			}
+		}
+
+		if best.Length < minMatch {
+			i++
+			continue
+		}
+
+		flush(i)
+		switch best.Kind {
+		case SourceCopy:
+			best.RelativeOffset = int64(pos) - sourceRel
+			sourceRel = int64(pos) + int64(best.Length)
+		case TargetCopy:
+			best.RelativeOffset = int64(pos) - targetRel
+			targetRel = int64(pos) + int64(best.Length)
+		}
+		p.Actions = append(p.Actions, best)
+
+		i += int(best.Length)
+		litStart = i
+	}
+	flush(len(target))
+
+	return
+}
+
+// CreateFromROMs diffs the contents of two ROMs
+func CreateFromROMs(source, target *snes.ROM, metadata []byte) (*Patch, error) {
+	return Create(source.Contents, target.Contents, metadata)
+}
@@ -0,0 +1,71 @@
+package snes
+
+// ComputeChecksum computes the internal checksum over the ROM contents.
+// ROM sizes that are not a power of two are summed as if the remainder were mirrored up to the next power of two,
+// e.g. a 3MiB ROM is summed as the first 2MiB plus twice the last 1MiB, matching how the cartridge is wired.
+func (r *ROM) ComputeChecksum() uint16 {
+	if len(r.Contents) == 0 {
+		return 0
+	}
+	return uint16(mirrorSum(r.Contents, highestPowerOfTwo(uint32(len(r.Contents)))))
+}
+
+// VerifyChecksum reports whether the header's checksum and its complement agree with the ROM contents
+func (r *ROM) VerifyChecksum() bool {
+	if r.Header.CheckSum^r.Header.ComplementCheckSum != 0xFFFF {
+		return false
+	}
+	return r.ComputeChecksum() == r.Header.CheckSum
+}
+
+// FixChecksum recomputes the checksum and its complement and writes them to the header in the ROM contents
+func (r *ROM) FixChecksum() (err error) {
+	// checksum and complement always sum to $1FE bytewise, so seed them with a consistent pair first:
+	r.Header.CheckSum = 0x0000
+	r.Header.ComplementCheckSum = 0xFFFF
+	if err = r.WriteHeader(); err != nil {
+		return
+	}
+
+	sum := r.ComputeChecksum()
+	r.Header.CheckSum = sum
+	r.Header.ComplementCheckSum = ^sum
+	err = r.WriteHeader()
+	return
+}
+
+func highestPowerOfTwo(n uint32) uint32 {
+	mask := uint32(1) << 31
+	for mask != 0 && n&mask == 0 {
+		mask >>= 1
+	}
+	return mask
+}
+
+func byteSum(b []byte) (sum uint32) {
+	for _, x := range b {
+		sum += uint32(x)
+	}
+	return
+}
+
+// mirrorSum sums the first mask bytes and then recursively sums the remainder, doubling it until it fills mask bytes
+func mirrorSum(b []byte, mask uint32) uint32 {
+	length := uint32(len(b))
+	for mask != 0 && length&mask == 0 {
+		mask >>= 1
+	}
+
+	sum := byteSum(b[:mask])
+	next := length - mask
+	if next != 0 {
+		part := mirrorSum(b[mask:], mask>>1)
+		for next < mask {
+			next += next
+			part += part
+		}
+		sum += part
+	}
+
+	return sum
+}
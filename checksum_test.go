@@ -0,0 +1,114 @@
+package snes
+
+import "testing"
+
+func TestROM_ComputeChecksum(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents func() []byte
+		want     uint16
+	}{
+		{
+			name: "1MiB power of two",
+			contents: func() []byte {
+				b := make([]byte, 0x100000)
+				b[0] = 0x01
+				b[0xFFFFF] = 0x02
+				return b
+			},
+			want: 0x0003,
+		},
+		{
+			name: "3MiB mirrors last 1MiB twice",
+			contents: func() []byte {
+				b := make([]byte, 0x300000)
+				b[0] = 0x01
+				b[0x200000] = 0x10
+				return b
+			},
+			want: 0x0001 + 0x0010*2,
+		},
+		{
+			name: "6MiB mirrors last 2MiB twice",
+			contents: func() []byte {
+				b := make([]byte, 0x600000)
+				b[0x3FFFFF] = 0x01
+				b[0x400000] = 0x20
+				return b
+			},
+			want: 0x0001 + 0x0020*2,
+		},
+		{
+			name: "12MiB mirrors last 4MiB twice",
+			contents: func() []byte {
+				b := make([]byte, 0xC00000)
+				b[0x7FFFFF] = 0x01
+				b[0xBFFFFF] = 0x30
+				return b
+			},
+			want: 0x0001 + 0x0030*2,
+		},
+		{
+			name: "1.25MiB mirrors last 256KiB four times",
+			contents: func() []byte {
+				b := make([]byte, 0x140000)
+				b[0x100000] = 0x01
+				return b
+			},
+			want: 0x0004,
+		},
+		{
+			name: "sum wraps at 16 bits",
+			contents: func() []byte {
+				b := make([]byte, 0x8000)
+				for i := 0; i < 0x102; i++ {
+					b[i] = 0xFF
+				}
+				return b
+			},
+			want: (0x102 * 0xFF) & 0xFFFF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ROM{Contents: tt.contents()}
+			if got := r.ComputeChecksum(); got != tt.want {
+				t.Errorf("ComputeChecksum() = %04x, want %04x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestROM_FixChecksum(t *testing.T) {
+	rom, err := NewROM("", sampleROM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rom.VerifyChecksum() {
+		t.Fatal("expected sample ROM checksum to be invalid")
+	}
+
+	if err = rom.FixChecksum(); err != nil {
+		t.Fatal(err)
+	}
+	if !rom.VerifyChecksum() {
+		t.Fatal("expected checksum to be valid after FixChecksum")
+	}
+
+	// re-read the header from contents and verify again:
+	if err = rom.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if rom.Header.CheckSum^rom.Header.ComplementCheckSum != 0xFFFF {
+		t.Fatal("expected complement to be written to contents")
+	}
+	if !rom.VerifyChecksum() {
+		t.Fatal("expected checksum written to contents to be valid")
+	}
+
+	// patching a byte invalidates the checksum:
+	rom.Contents[0x1234] ^= 0xFF
+	if rom.VerifyChecksum() {
+		t.Fatal("expected checksum to be invalid after patching")
+	}
+}
@@ -0,0 +1,133 @@
+package snes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// CopierHeaderSize is the size of the header that backup units (copiers) prepend to ROM dumps
+const CopierHeaderSize = 0x200
+
+type CopierFormat uint8
+
+const (
+	CopierUnknown CopierFormat = iota
+	CopierSWC                  // Super Wild Card / Super Magicom (SMC)
+	CopierFIG                  // Pro Fighter
+	CopierGD3                  // Game Doctor SF 3/6/7
+)
+
+var copierFormatNames = map[CopierFormat]string{
+	CopierUnknown: "Unknown",
+	CopierSWC:     "SWC",
+	CopierFIG:     "FIG",
+	CopierGD3:     "GD3",
+}
+
+func (f CopierFormat) String() string {
+	if s, ok := copierFormatNames[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("CopierFormat(%d)", uint8(f))
+}
+
+var gd3Signature = []byte("GAME DOCTOR SF 3")
+
+// CopierHeader is the 512-byte header found at the start of ROM dumps made with backup units.
+// Raw is always written back out unmodified so round-trips are lossless.
+type CopierHeader struct {
+	Format CopierFormat
+
+	// Blocks is the ROM size in 8KiB units as declared by SWC and FIG headers:
+	Blocks uint16
+	// Split is set if more files of a multi-file dump follow this one (SWC and FIG):
+	Split bool
+	// HiROM is set if the header declares a HiROM layout (FIG):
+	HiROM bool
+
+	Raw [CopierHeaderSize]byte
+}
+
+// ParseCopierHeader parses the first 512 bytes of b as a copier header
+func ParseCopierHeader(b []byte) (c *CopierHeader, err error) {
+	if len(b) < CopierHeaderSize {
+		return nil, fmt.Errorf("copier header must be %d bytes", CopierHeaderSize)
+	}
+
+	c = &CopierHeader{}
+	copy(c.Raw[:], b[:CopierHeaderSize])
+	c.Format = identifyCopierHeader(c.Raw[:])
+
+	switch c.Format {
+	case CopierSWC:
+		c.Blocks = binary.LittleEndian.Uint16(c.Raw[0:2])
+		c.Split = c.Raw[2]&0x40 != 0
+	case CopierFIG:
+		c.Blocks = binary.LittleEndian.Uint16(c.Raw[0:2])
+		c.Split = c.Raw[2]&0x40 != 0
+		c.HiROM = c.Raw[3]&0x80 != 0
+	}
+
+	return
+}
+
+func identifyCopierHeader(h []byte) CopierFormat {
+	if bytes.Equal(h[0:len(gd3Signature)], gd3Signature) {
+		return CopierGD3
+	}
+
+	// SWC has a fixed signature:
+	if h[8] == 0xAA && h[9] == 0xBB && h[10] == 0x04 {
+		return CopierSWC
+	}
+
+	// FIG only has a few flag bytes with known values; the rest of the header is zeroed:
+	if (h[2] == 0x00 || h[2] == 0x40) && (h[3] == 0x00 || h[3] == 0x80) {
+		switch uint16(h[4])<<8 | uint16(h[5]) {
+		case 0x0000, 0x0080, 0x7783, 0x4783, 0x1102, 0xDD82, 0xDD02, 0xFD82:
+			if isZero(h[6:]) {
+				return CopierFIG
+			}
+		}
+	}
+
+	return CopierUnknown
+}
+
+func isZero(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// detectCopierHeader determines if the file contents start with a copier header
+func detectCopierHeader(contents []byte) bool {
+	if len(contents) < CopierHeaderSize {
+		return false
+	}
+
+	// ROM dumps are always a multiple of 32KiB in size:
+	rem := len(contents) & 0x7FFF
+	if rem == CopierHeaderSize {
+		return true
+	}
+	if rem == 0 {
+		return false
+	}
+
+	// odd sized dumps still carry a header if it has a recognizable layout:
+	return rem&0x3FF == CopierHeaderSize && identifyCopierHeader(contents[:CopierHeaderSize]) != CopierUnknown
+}
+
+// WriteTo writes out the copier header unmodified
+func (c *CopierHeader) WriteTo(w io.Writer) (n int64, err error) {
+	var m int
+	m, err = w.Write(c.Raw[:])
+	n = int64(m)
+	return
+}
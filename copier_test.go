@@ -0,0 +1,105 @@
+package snes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewROM_CopierHeader(t *testing.T) {
+	swc := make([]byte, CopierHeaderSize)
+	swc[0], swc[1] = 0x08, 0x00
+	swc[8], swc[9], swc[10] = 0xAA, 0xBB, 0x04
+
+	fig := make([]byte, CopierHeaderSize)
+	fig[0], fig[1] = 0x08, 0x00
+	fig[3], fig[4], fig[5] = 0x80, 0xDD, 0x82
+
+	gd3 := make([]byte, CopierHeaderSize)
+	copy(gd3, "GAME DOCTOR SF 3")
+
+	unknown := make([]byte, CopierHeaderSize)
+	unknown[0x1FF] = 0x5A
+
+	tests := []struct {
+		name       string
+		header     []byte
+		wantFormat CopierFormat
+		wantBlocks uint16
+		wantHiROM  bool
+	}{
+		{
+			name:       "SWC",
+			header:     swc,
+			wantFormat: CopierSWC,
+			wantBlocks: 8,
+		},
+		{
+			name:       "FIG",
+			header:     fig,
+			wantFormat: CopierFIG,
+			wantBlocks: 8,
+			wantHiROM:  true,
+		},
+		{
+			name:       "GD3",
+			header:     gd3,
+			wantFormat: CopierGD3,
+		},
+		{
+			name:       "Unknown",
+			header:     unknown,
+			wantFormat: CopierUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := append(append([]byte{}, tt.header...), sampleROM()...)
+
+			rom, err := NewROM("", file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rom.Copier == nil {
+				t.Fatal("expected copier header to be detected")
+			}
+			if rom.Copier.Format != tt.wantFormat {
+				t.Errorf("Format = %v, want %v", rom.Copier.Format, tt.wantFormat)
+			}
+			if rom.Copier.Blocks != tt.wantBlocks {
+				t.Errorf("Blocks = %v, want %v", rom.Copier.Blocks, tt.wantBlocks)
+			}
+			if rom.Copier.HiROM != tt.wantHiROM {
+				t.Errorf("HiROM = %v, want %v", rom.Copier.HiROM, tt.wantHiROM)
+			}
+			if len(rom.Contents) != 0x10000 {
+				t.Errorf("len(Contents) = %x, want %x", len(rom.Contents), 0x10000)
+			}
+			if rom.Header.NativeVectors.NMI != 0x80c9 {
+				t.Error("NativeVectors.NMI")
+			}
+
+			// round-trip must be lossless:
+			b := &bytes.Buffer{}
+			n, err := rom.WriteTo(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(len(file)) {
+				t.Errorf("WriteTo() = %d, want %d", n, len(file))
+			}
+			if !bytes.Equal(b.Bytes(), file) {
+				t.Error("WriteTo() output differs from input")
+			}
+		})
+	}
+}
+
+func TestNewROM_NoCopierHeader(t *testing.T) {
+	rom, err := NewROM("", sampleROM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rom.Copier != nil {
+		t.Fatal("expected no copier header")
+	}
+}
@@ -38,7 +38,13 @@ import (
 type Bus struct {
 	EA      uint32                 // last memory access - r/w
 	Write   bool                   // is write op?
+	M       uint8                  // last value on the data bus; reads of unmapped addresses return it (open bus)
 	segment [1048576]memory.Memory // 2^10 because segments are 4bits length
+
+	Unmapped   uint64                     // number of accesses to unmapped addresses
+	OnUnmapped func(a uint32, write bool) // optional; called for each access to an unmapped address
+
+	observers []*Observer // nil unless Observe was called; keeps the fast path to one check
 }
 
 func (b *Bus) String() string {
@@ -85,11 +91,31 @@ func (b *Bus) Attach(mem memory.Memory, name string, start uint32, end uint32) e
 func (b *Bus) EaRead(a uint32) byte {
 	mem := b.segment[a>>4]
 	if mem == nil {
-		panic(fmt.Errorf("No backend for address 0x%06X index %06x", a, a>>4))
+		return b.unmappedRead(a)
+	}
+	b.EA = a // for debug interface
+	b.Write = false
+	value := mem.Read(a)
+	b.M = value
+	if b.observers != nil {
+		b.notify(a, value, Read)
+	}
+	return value
+}
+
+// EaFetch reads an opcode byte like EaRead but reports it to observers as Execute
+func (b *Bus) EaFetch(a uint32) byte {
+	mem := b.segment[a>>4]
+	if mem == nil {
+		return b.unmappedRead(a)
 	}
 	b.EA = a // for debug interface
 	b.Write = false
 	value := mem.Read(a)
+	b.M = value
+	if b.observers != nil {
+		b.notify(a, value, Execute)
+	}
 	return value
 }
 
@@ -101,8 +127,11 @@ func (b *Bus) EaRead24_wrap(bank byte, addr uint16) uint32 {
 	m1 := b.segment[(bank32|offs+1)>>4]
 	m2 := b.segment[(bank32|offs+2)>>4]
 	if m0 == nil || m1 == nil || m2 == nil {
-		a := bank32 | (offs + 0)
-		panic(fmt.Errorf("No backend for address 0x%06X index %06x", a, a>>4))
+		// open bus for some of the bytes:
+		ll := b.EaRead(bank32 | (offs + 0))
+		mm := b.EaRead(bank32 | (offs + 1))
+		hh := b.EaRead(bank32 | (offs + 2))
+		return uint32(hh)<<16 | uint32(mm)<<8 | uint32(ll)
 	}
 
 	b.Write = false
@@ -112,6 +141,13 @@ func (b *Bus) EaRead24_wrap(bank byte, addr uint16) uint32 {
 	mm := m1.Read(b.EA)
 	b.EA = bank32 | (offs + 2)
 	hh := m2.Read(b.EA)
+	b.M = hh
+
+	if b.observers != nil {
+		b.notify(bank32|(offs+0), ll, Read)
+		b.notify(bank32|(offs+1), mm, Read)
+		b.notify(bank32|(offs+2), hh, Read)
+	}
 
 	return uint32(hh)<<16 | uint32(mm)<<8 | uint32(ll)
 }
@@ -120,11 +156,43 @@ func (b *Bus) EaRead24_wrap(bank byte, addr uint16) uint32 {
 func (b *Bus) EaWrite(a uint32, value byte) {
 	mem := b.segment[a>>4]
 	if mem == nil {
-		panic(fmt.Errorf("No backend for address 0x%06X index %06x", a, a>>4))
+		b.unmappedWrite(a, value)
+		return
 	}
 	b.EA = a // for debug interface
 	b.Write = true
+	b.M = value
 	mem.Write(a, value)
+	if b.observers != nil {
+		b.notify(a, value, Write)
+	}
+}
+
+// unmappedRead counts a read of an address with no backend and returns the open bus value M. It is kept out of line
+// so that the mapped path of EaRead stays as small as before open bus was added.
+//
+//go:noinline
+func (b *Bus) unmappedRead(a uint32) byte {
+	b.EA = a
+	b.Write = false
+	b.Unmapped++
+	if b.OnUnmapped != nil {
+		b.OnUnmapped(a, false)
+	}
+	return b.M
+}
+
+// unmappedWrite counts a dropped write to an address with no backend; the value is left on the open bus
+//
+//go:noinline
+func (b *Bus) unmappedWrite(a uint32, value byte) {
+	b.EA = a
+	b.Write = true
+	b.M = value
+	b.Unmapped++
+	if b.OnUnmapped != nil {
+		b.OnUnmapped(a, true)
+	}
 }
 
 func (b *Bus) EaDump(start uint32, end uint32, data []byte) int {
@@ -2,6 +2,7 @@ package bus
 
 import (
 	"github.com/alttpo/snes/emulator/memory"
+	"reflect"
 	"testing"
 )
 
@@ -109,3 +110,51 @@ func TestBus_EaDump_32(t *testing.T) {
 		t.Fatal(n)
 	}
 }
+
+func TestBus_OpenBus(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ram := make([]byte, 0x10)
+	ram[0xF] = 0x42
+	if err = b.Attach(memory.NewRAM(ram, 0), "ram", 0x00_0000, 0x00_000F); err != nil {
+		t.Fatal(err)
+	}
+
+	type access struct {
+		a     uint32
+		write bool
+	}
+	var unmapped []access
+	b.OnUnmapped = func(a uint32, write bool) { unmapped = append(unmapped, access{a, write}) }
+
+	if v := b.EaRead(0x00_000F); v != 0x42 {
+		t.Fatalf("EaRead(mapped) = %02X, want 42", v)
+	}
+	if v := b.EaRead(0x00_0010); v != 0x42 {
+		t.Errorf("EaRead(unmapped) = %02X, want open bus 42", v)
+	}
+	b.EaWrite(0x01_0000, 0x99)
+	if v := b.EaFetch(0x01_0000); v != 0x99 {
+		t.Errorf("EaFetch(unmapped) = %02X, want open bus 99", v)
+	}
+	// the mapped first byte drives the bus for the rest:
+	if v := b.EaRead24_wrap(0x00, 0x000F); v != 0x424242 {
+		t.Errorf("EaRead24_wrap() = %06X, want 424242", v)
+	}
+
+	want := []access{
+		{0x00_0010, false},
+		{0x01_0000, true},
+		{0x01_0000, false},
+		{0x00_0010, false},
+		{0x00_0011, false},
+	}
+	if !reflect.DeepEqual(unmapped, want) {
+		t.Errorf("OnUnmapped calls = %v, want %v", unmapped, want)
+	}
+	if b.Unmapped != uint64(len(want)) {
+		t.Errorf("Unmapped = %d, want %d", b.Unmapped, len(want))
+	}
+}
@@ -0,0 +1,100 @@
+package bus
+
+// Access is a set of bus access kinds
+type Access uint8
+
+const (
+	Read    Access = 1 << iota // data reads, including instruction operands
+	Write                      // writes
+	Execute                    // opcode fetches made through EaFetch
+
+	All = Read | Write | Execute
+)
+
+func (k Access) String() string {
+	switch k {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	case Execute:
+		return "execute"
+	}
+	s := ""
+	for _, kind := range [...]Access{Read, Write, Execute} {
+		if k&kind != 0 {
+			if s != "" {
+				s += "|"
+			}
+			s += kind.String()
+		}
+	}
+	return s
+}
+
+// ObserverFunc is called after an access of address a that carried value
+type ObserverFunc func(a uint32, value byte, kind Access)
+
+// Observer calls Func for the Kinds of accesses to addresses Start through End inclusive. Accesses are reported by
+// their bus address, so a mirror of the range is only seen if it is observed as well. EaDump is not observed.
+type Observer struct {
+	Kinds      Access
+	Start, End uint32
+	Func       ObserverFunc
+}
+
+// Observe registers fn for the kinds of accesses to start through end inclusive and returns the Observer to pass to
+// Unobserve. Observers are called in the order they were registered.
+func (b *Bus) Observe(kinds Access, start, end uint32, fn ObserverFunc) *Observer {
+	o := &Observer{Kinds: kinds, Start: start, End: end, Func: fn}
+	b.observers = append(b.observers, o)
+	return o
+}
+
+// Unobserve removes the observer; the bus returns to its fast path once none remain
+func (b *Bus) Unobserve(o *Observer) {
+	for i, x := range b.observers {
+		if x != o {
+			continue
+		}
+		b.observers = append(b.observers[:i:i], b.observers[i+1:]...)
+		break
+	}
+	if len(b.observers) == 0 {
+		b.observers = nil
+	}
+}
+
+func (b *Bus) notify(a uint32, value byte, kind Access) {
+	for _, o := range b.observers {
+		if o.Kinds&kind != 0 && a >= o.Start && a <= o.End {
+			o.Func(a, value, kind)
+		}
+	}
+}
+
+// LogEntry is one access recorded by AccessLog
+type LogEntry struct {
+	Address uint32
+	Value   byte
+	Kind    Access
+}
+
+// AccessLog records the accesses it observes in order, e.g. every write a routine performs:
+//
+//	var log bus.AccessLog
+//	o := b.Observe(bus.Write, 0x7E0000, 0x7FFFFF, log.Observe)
+//	defer b.Unobserve(o)
+type AccessLog struct {
+	Entries []LogEntry
+}
+
+// Observe is an ObserverFunc that appends the access to the log
+func (l *AccessLog) Observe(a uint32, value byte, kind Access) {
+	l.Entries = append(l.Entries, LogEntry{Address: a, Value: value, Kind: kind})
+}
+
+// Reset empties the log, keeping its storage
+func (l *AccessLog) Reset() {
+	l.Entries = l.Entries[:0]
+}
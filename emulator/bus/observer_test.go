@@ -0,0 +1,126 @@
+package bus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alttpo/snes/emulator/memory"
+)
+
+func newObserverTest(t testing.TB) *Bus {
+	b, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ram := make([]byte, 0x10000)
+	for i := range ram {
+		ram[i] = byte(i)
+	}
+	if err = b.Attach(memory.NewRAM(ram, 0), "ram", 0x00_0000, 0x00_FFFF); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBus_Observe(t *testing.T) {
+	b := newObserverTest(t)
+
+	var reads, writes, all AccessLog
+	b.Observe(Read, 0x0100, 0x01FF, reads.Observe)
+	b.Observe(Write, 0x0100, 0x01FF, writes.Observe)
+	o := b.Observe(All, 0x0000, 0xFFFF, all.Observe)
+
+	b.EaRead(0x0100)
+	b.EaRead(0x0200)
+	b.EaWrite(0x01FF, 0x55)
+	b.EaFetch(0x0180)
+	if v := b.EaRead24_wrap(0x00, 0x01FE); v != 0x0055FE {
+		t.Errorf("EaRead24_wrap() = %06X, want 0055FE", v)
+	}
+
+	wantReads := []LogEntry{
+		{0x0100, 0x00, Read},
+		{0x01FE, 0xFE, Read},
+		{0x01FF, 0x55, Read},
+	}
+	if !reflect.DeepEqual(reads.Entries, wantReads) {
+		t.Errorf("reads = %v, want %v", reads.Entries, wantReads)
+	}
+	wantWrites := []LogEntry{{0x01FF, 0x55, Write}}
+	if !reflect.DeepEqual(writes.Entries, wantWrites) {
+		t.Errorf("writes = %v, want %v", writes.Entries, wantWrites)
+	}
+	wantAll := []LogEntry{
+		{0x0100, 0x00, Read},
+		{0x0200, 0x00, Read},
+		{0x01FF, 0x55, Write},
+		{0x0180, 0x80, Execute},
+		{0x01FE, 0xFE, Read},
+		{0x01FF, 0x55, Read},
+		{0x0200, 0x00, Read},
+	}
+	if !reflect.DeepEqual(all.Entries, wantAll) {
+		t.Errorf("all = %v, want %v", all.Entries, wantAll)
+	}
+
+	// dumps are not observed and removed observers are not called:
+	all.Reset()
+	b.Unobserve(o)
+	b.EaDump(0x0000, 0x000F, make([]byte, 0x10))
+	b.EaRead(0x0000)
+	if len(all.Entries) != 0 {
+		t.Errorf("after Unobserve all = %v, want none", all.Entries)
+	}
+}
+
+func TestBus_Unobserve(t *testing.T) {
+	b := newObserverTest(t)
+
+	var log AccessLog
+	o1 := b.Observe(Read, 0, 0xFFFF, log.Observe)
+	o2 := b.Observe(Read, 0, 0xFFFF, log.Observe)
+	b.Unobserve(o1)
+	b.EaRead(0x10)
+	if len(log.Entries) != 1 {
+		t.Errorf("entries = %v, want one", log.Entries)
+	}
+	b.Unobserve(o2)
+	if b.observers != nil {
+		t.Errorf("observers = %v, want nil", b.observers)
+	}
+}
+
+func TestAccess_String(t *testing.T) {
+	tests := []struct {
+		kind Access
+		want string
+	}{
+		{Read, "read"},
+		{Execute, "execute"},
+		{Read | Write, "read|write"},
+		{All, "read|write|execute"},
+	}
+	for _, tt := range tests {
+		if got := tt.kind.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func BenchmarkBus_EaRead(b *testing.B) {
+	bus := newObserverTest(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.EaRead(uint32(i) & 0xFFFF)
+	}
+}
+
+func BenchmarkBus_EaRead_Observed(b *testing.B) {
+	bus := newObserverTest(b)
+	n := 0
+	bus.Observe(Write, 0x7E_0000, 0x7F_FFFF, func(uint32, byte, Access) { n++ })
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.EaRead(uint32(i) & 0xFFFF)
+	}
+}
@@ -0,0 +1,109 @@
+package emulator
+
+import (
+	"fmt"
+
+	"github.com/alttpo/snes"
+	"github.com/alttpo/snes/emulator/memory"
+	"github.com/alttpo/snes/mapping"
+	"github.com/alttpo/snes/mapping/util"
+)
+
+// NewSystem creates a System whose bus follows the ROM's cartridge layout. The Mapper is selected from the header
+// and ROM and SRAM are mirrored according to the header's ROM and RAM sizes.
+func NewSystem(rom *snes.ROM) (s *System, err error) {
+	if len(rom.Contents) == 0 {
+		return nil, fmt.Errorf("emulator: empty ROM")
+	}
+
+	s = &System{}
+	if s.Mapper, err = mapping.ForHeader(&rom.Header); err != nil {
+		return nil, err
+	}
+	copy(s.ROM[:], rom.Contents)
+
+	// ROM mirrors by the larger of the declared size and the actual size, rounded up to a power of two. A declared
+	// size beyond the 16MiB of ROM space is invalid and falls back to the actual size:
+	romSize := uint32(1)
+	if rom.Header.ROMSize <= 0x0E {
+		romSize = rom.Header.ROMSizeBytes()
+	}
+	for romSize < uint32(len(rom.Contents)) && romSize < uint32(len(s.ROM)) {
+		romSize <<= 1
+	}
+	if romSize > uint32(len(s.ROM)) {
+		romSize = uint32(len(s.ROM))
+	}
+
+	// a RAM size of 0 means no SRAM:
+	sramSize := SRAMSize(&rom.Header)
+	if sramSize != 0 {
+		s.SRAM = make([]byte, sramSize)
+	}
+
+	s.CPU.Init(&s.Bus)
+
+	for _, seg := range s.Mapper.SplitBusRange(0, 0x1000000) {
+		switch util.PakRegionKind(seg.Pak) {
+		case util.RegionROM:
+			err = s.attachROM(seg, romSize)
+		case util.RegionSRAM:
+			if sramSize == 0 {
+				continue
+			}
+			err = s.attachRAM(seg, "sram", areaSRAM, sramSize, 0xE00000)
+		case util.RegionWRAM:
+			err = s.attachRAM(seg, "wram", areaWRAM, uint32(len(s.WRAM)), 0xF50000)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Memory-mapped IO registers:
+	hwio := &memory.FakeHW{}
+	s.HWIO = hwio
+	for b := uint32(0); b < 0x100; b++ {
+		bank := b << 16
+		if !util.IsSystemArea(bank | 0x2000) {
+			continue
+		}
+		if err = s.Bus.Attach(hwio, "hwio", bank|0x2000, bank|0x5FFF); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// forMirrors calls attach for each part of a bus segment, wrapping pak addresses relative to pakBase around size
+func forMirrors(seg util.Segment, size, pakBase uint32, attach func(bus, offs, n uint32) error) (err error) {
+	bus := seg.Bus
+	offs := seg.Pak - pakBase
+	for remaining := seg.Size; remaining > 0; {
+		offs %= size
+		n := size - offs
+		if n > remaining {
+			n = remaining
+		}
+		if err = attach(bus, offs, n); err != nil {
+			return
+		}
+		bus += n
+		offs += n
+		remaining -= n
+	}
+	return
+}
+
+func (s *System) attachROM(seg util.Segment, size uint32) error {
+	return forMirrors(seg, size, 0, func(bus, offs, n uint32) error {
+		return s.Bus.Attach(memory.NewROM(s.ROM[offs:offs+n], bus), "rom", bus, bus+n-1)
+	})
+}
+
+func (s *System) attachRAM(seg util.Segment, name string, a ramArea, size, pakBase uint32) error {
+	return forMirrors(seg, size, pakBase, func(bus, offs, n uint32) error {
+		return s.Bus.Attach(s.newRAM(a, offs, offs+n, bus), name, bus, bus+n-1)
+	})
+}
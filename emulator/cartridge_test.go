@@ -0,0 +1,150 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes"
+)
+
+func TestNewSystem(t *testing.T) {
+	tests := []struct {
+		name   string
+		header snes.Header
+		size   int
+		verify func(t *testing.T, q *System)
+	}{
+		{
+			name:   "LoROM ROM mirrors by ROM size",
+			header: snes.Header{MapMode: 0x20, ROMSize: 0x0A, RAMSize: 0x03},
+			size:   0x100000,
+			verify: func(t *testing.T, q *System) {
+				q.ROM[0x8000] = 0xFD
+				for _, a := range []uint32{0x01_8000, 0x81_8000, 0x21_8000, 0xA1_8000} {
+					if actual, expected := q.Bus.EaRead(a), uint8(0xFD); actual != expected {
+						t.Errorf("$%06X: actual = %v, expected = %v", a, actual, expected)
+					}
+				}
+			},
+		},
+		{
+			name:   "invalid ROM size mirrors by actual size",
+			header: snes.Header{MapMode: 0x20, ROMSize: 0x16},
+			size:   0x8000,
+			verify: func(t *testing.T, q *System) {
+				q.ROM[0x0000] = 0xF8
+				for _, a := range []uint32{0x00_8000, 0x01_8000, 0x3F_8000} {
+					if actual, expected := q.Bus.EaRead(a), uint8(0xF8); actual != expected {
+						t.Errorf("$%06X: actual = %v, expected = %v", a, actual, expected)
+					}
+				}
+			},
+		},
+		{
+			name:   "LoROM SRAM mirrors by RAM size",
+			header: snes.Header{MapMode: 0x20, ROMSize: 0x0A, RAMSize: 0x03},
+			size:   0x100000,
+			verify: func(t *testing.T, q *System) {
+				q.Bus.EaWrite(0x70_0010, 0xFC)
+				if q.SRAM[0x10] != 0xFC {
+					t.Errorf("SRAM write failed, actual = %v", q.SRAM[0x10])
+				}
+				if actual, expected := q.Bus.EaRead(0x70_2010), uint8(0xFC); actual != expected {
+					t.Errorf("mirror failed, actual = %v, expected = %v", actual, expected)
+				}
+			},
+		},
+		{
+			name:   "HiROM ROM",
+			header: snes.Header{MapMode: 0x21, ROMSize: 0x0B, RAMSize: 0x03},
+			size:   0x200000,
+			verify: func(t *testing.T, q *System) {
+				q.ROM[0x012345] = 0xFE
+				for _, a := range []uint32{0xC1_2345, 0x41_2345, 0xE1_2345} {
+					if actual, expected := q.Bus.EaRead(a), uint8(0xFE); actual != expected {
+						t.Errorf("$%06X: actual = %v, expected = %v", a, actual, expected)
+					}
+				}
+			},
+		},
+		{
+			name:   "HiROM SRAM",
+			header: snes.Header{MapMode: 0x21, ROMSize: 0x0B, RAMSize: 0x03},
+			size:   0x200000,
+			verify: func(t *testing.T, q *System) {
+				q.SRAM[0x0123] = 0xFB
+				for _, a := range []uint32{0x20_6123, 0xA0_6123, 0x21_6123} {
+					if actual, expected := q.Bus.EaRead(a), uint8(0xFB); actual != expected {
+						t.Errorf("$%06X: actual = %v, expected = %v", a, actual, expected)
+					}
+				}
+			},
+		},
+		{
+			name:   "HiROM WRAM",
+			header: snes.Header{MapMode: 0x31, ROMSize: 0x0B},
+			size:   0x200000,
+			verify: func(t *testing.T, q *System) {
+				q.Bus.EaWrite(0x7E_0100, 0xFA)
+				if actual, expected := q.Bus.EaRead(0x80_0100), uint8(0xFA); actual != expected {
+					t.Errorf("mirror failed, actual = %v, expected = %v", actual, expected)
+				}
+			},
+		},
+		{
+			name:   "HiROM subroutine",
+			header: snes.Header{MapMode: 0x21, ROMSize: 0x0B},
+			size:   0x200000,
+			verify: func(t *testing.T, q *System) {
+				// LDA #$1342; STA $7E0000; NOP
+				copy(q.ROM[0x8000:], []byte{0xA9, 0x42, 0x13, 0x8F, 0x00, 0x00, 0x7E, 0xEA})
+				q.SetPC(0xC0_8000)
+				if !q.RunUntil(0xC0_8007, 100) {
+					t.Fatalf("did not reach target, PC = $%06X", q.GetPC())
+				}
+				if q.WRAM[0] != 0x42 || q.WRAM[1] != 0x13 {
+					t.Errorf("WRAM[0:2] = $%02X, expected $42 $13", q.WRAM[0:2])
+				}
+			},
+		},
+		{
+			name:   "SA-1 BW-RAM",
+			header: snes.Header{MapMode: 0x23, CartridgeType: 0x35, ROMSize: 0x0B, RAMSize: 0x05},
+			size:   0x200000,
+			verify: func(t *testing.T, q *System) {
+				q.Bus.EaWrite(0x40_1234, 0xF9)
+				if actual, expected := q.Bus.EaRead(0x00_7234), uint8(0xF9); actual != expected {
+					t.Errorf("BW-RAM image failed, actual = %v, expected = %v", actual, expected)
+				}
+			},
+		},
+		{
+			name:   "hardware registers",
+			header: snes.Header{MapMode: 0x21, ROMSize: 0x0B},
+			size:   0x200000,
+			verify: func(t *testing.T, q *System) {
+				q.Bus.EaWrite(0x00_4200, 0x81)
+				if actual, expected := q.Bus.EaRead(0x80_4200), uint8(0x81); actual != expected {
+					t.Errorf("mirror failed, actual = %v, expected = %v", actual, expected)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rom := &snes.ROM{Contents: make([]byte, tt.size), Header: tt.header}
+			q, err := NewSystem(rom)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.verify(t, q)
+		})
+	}
+}
+
+func TestNewSystem_UnknownMapMode(t *testing.T) {
+	rom := &snes.ROM{Contents: make([]byte, 0x8000), Header: snes.Header{MapMode: 0x2B}}
+	if _, err := NewSystem(rom); err == nil {
+		t.Error("expected error for unknown map mode")
+	}
+}
@@ -0,0 +1,169 @@
+package emulator
+
+import (
+	"encoding"
+	"errors"
+
+	"github.com/alttpo/snes/emulator/cpu65c816"
+	"github.com/alttpo/snes/emulator/memory"
+)
+
+const checkpointPageSize = 0x1000
+
+var ErrCheckpointReleased = errors.New("emulator: checkpoint was released")
+
+// ramArea identifies the System memory a cowRAM writes to
+type ramArea int
+
+const (
+	areaWRAM ramArea = iota
+	areaSRAM
+	areaCount
+)
+
+func (s *System) area(a ramArea) []byte {
+	switch a {
+	case areaWRAM:
+		return s.WRAM[:]
+	case areaSRAM:
+		return s.SRAM
+	}
+	return nil
+}
+
+// Checkpoint is an in-memory copy-on-write checkpoint of a System. Only pages written through the bus after the
+// checkpoint are copied; writes made directly to the System's arrays are not tracked. Restore, LoadSRAM and MountSRAM
+// preserve every page they overwrite, so rewinding to a checkpoint taken before them undoes them as well.
+type Checkpoint struct {
+	cpu   cpu65c816.CPU
+	hw    []byte
+	pages [areaCount]map[uint32][]byte
+}
+
+// cowRAM preserves pages for active checkpoints before writing through
+type cowRAM struct {
+	memory.RAM
+	s      *System
+	area   ramArea
+	base   uint32
+	offset uint32
+}
+
+func (s *System) newRAM(a ramArea, start, end uint32, offset uint32) memory.Memory {
+	return cowRAM{
+		RAM:    memory.NewRAM(s.area(a)[start:end], offset),
+		s:      s,
+		area:   a,
+		base:   start,
+		offset: offset,
+	}
+}
+
+func (m cowRAM) Write(address uint32, value byte) {
+	if len(m.s.checkpoints) != 0 {
+		m.s.preserve(m.area, m.base+address-m.offset)
+	}
+	if m.area == areaSRAM && m.s.sramDirty != nil {
+		m.s.sramDirty[(m.base+address-m.offset)/sramPageSize] = true
+	}
+	m.RAM.Write(address, value)
+}
+
+func (s *System) preserve(a ramArea, index uint32) {
+	page := index / checkpointPageSize
+	for _, cp := range s.checkpoints {
+		if _, ok := cp.pages[a][page]; ok {
+			continue
+		}
+		start := page * checkpointPageSize
+		saved := make([]byte, checkpointPageSize)
+		copy(saved, s.area(a)[start:])
+		cp.pages[a][page] = saved
+	}
+}
+
+// preserveArea preserves every page of the area for the active checkpoints before it is overwritten wholesale
+func (s *System) preserveArea(a ramArea) {
+	if len(s.checkpoints) == 0 {
+		return
+	}
+	for index := uint32(0); index < uint32(len(s.area(a))); index += checkpointPageSize {
+		s.preserve(a, index)
+	}
+}
+
+// Checkpoint records the current state so that Rewind can return to it
+func (s *System) Checkpoint() (cp *Checkpoint, err error) {
+	cp = &Checkpoint{cpu: s.CPU}
+	for a := range cp.pages {
+		cp.pages[a] = make(map[uint32][]byte)
+	}
+	if m, ok := s.HWIO.(encoding.BinaryMarshaler); ok {
+		if cp.hw, err = m.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	s.checkpoints = append(s.checkpoints, cp)
+	return
+}
+
+// Rewind returns the System to the state at the checkpoint. The checkpoint stays usable; checkpoints taken after it
+// are released.
+func (s *System) Rewind(cp *Checkpoint) (err error) {
+	i := s.checkpointIndex(cp)
+	if i < 0 {
+		return ErrCheckpointReleased
+	}
+
+	if cp.hw != nil {
+		if u, ok := s.HWIO.(encoding.BinaryUnmarshaler); ok {
+			if err = u.UnmarshalBinary(cp.hw); err != nil {
+				return
+			}
+		}
+	}
+	for a := range cp.pages {
+		data := s.area(ramArea(a))
+		for page, saved := range cp.pages[a] {
+			start := page * checkpointPageSize
+			copy(data[start:], saved)
+			if ramArea(a) == areaSRAM {
+				s.markSRAMDirty(start, start+checkpointPageSize)
+			}
+		}
+		cp.pages[a] = make(map[uint32][]byte)
+	}
+
+	// keep the callbacks installed on the CPU now:
+	cpu := cp.cpu
+	cpu.Bus = s.CPU.Bus
+	cpu.OnWDM = s.CPU.OnWDM
+	cpu.OnPC = s.CPU.OnPC
+	cpu.OnAccess = s.CPU.OnAccess
+	s.CPU = cpu
+
+	for _, later := range s.checkpoints[i+1:] {
+		later.pages = [areaCount]map[uint32][]byte{}
+	}
+	s.checkpoints = s.checkpoints[:i+1]
+	return
+}
+
+// Release stops tracking writes for the checkpoint
+func (s *System) Release(cp *Checkpoint) {
+	i := s.checkpointIndex(cp)
+	if i < 0 {
+		return
+	}
+	s.checkpoints = append(s.checkpoints[:i], s.checkpoints[i+1:]...)
+	cp.pages = [areaCount]map[uint32][]byte{}
+}
+
+func (s *System) checkpointIndex(cp *Checkpoint) int {
+	for i, c := range s.checkpoints {
+		if c == cp {
+			return i
+		}
+	}
+	return -1
+}
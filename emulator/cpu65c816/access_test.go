@@ -0,0 +1,84 @@
+package cpu65c816
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alttpo/snes/emulator/bus"
+	"github.com/alttpo/snes/emulator/memory"
+)
+
+type testAccess struct {
+	ea    uint32
+	value byte
+	kind  AccessKind
+}
+
+func TestCPU_OnAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		m, x byte
+		code []byte
+		want []testAccess
+	}{
+		{
+			// data read right after the instruction is still a read:
+			"LDA abs near PC", 1, 1, []byte{0xAD, 0x03, 0x80, 0x42},
+			[]testAccess{
+				{0x00_8000, 0xAD, AccessFetch}, {0x00_8001, 0x03, AccessFetch}, {0x00_8002, 0x80, AccessFetch},
+				{0x00_8003, 0x42, AccessRead},
+			},
+		},
+		{
+			"LDA immediate", 0, 1, []byte{0xA9, 0x34, 0x12},
+			[]testAccess{
+				{0x00_8000, 0xA9, AccessFetch}, {0x00_8001, 0x34, AccessFetch}, {0x00_8002, 0x12, AccessFetch},
+			},
+		},
+		{
+			"STA long", 1, 1, []byte{0x8F, 0x10, 0x00, 0x7E},
+			[]testAccess{
+				{0x00_8000, 0x8F, AccessFetch}, {0x00_8001, 0x10, AccessFetch}, {0x00_8002, 0x00, AccessFetch},
+				{0x00_8003, 0x7E, AccessFetch},
+				{0x7E_0010, 0x00, AccessWrite},
+			},
+		},
+		{
+			"SEP", 1, 1, []byte{0xE2, 0x30},
+			[]testAccess{{0x00_8000, 0xE2, AccessFetch}, {0x00_8001, 0x30, AccessFetch}},
+		},
+		{
+			"MVN", 1, 1, []byte{0x54, 0x7E, 0x7E},
+			[]testAccess{
+				{0x00_8000, 0x54, AccessFetch}, {0x00_8001, 0x7E, AccessFetch}, {0x00_8002, 0x7E, AccessFetch},
+				{0x7E_0000, 0x00, AccessRead}, {0x7E_0000, 0x00, AccessWrite},
+			},
+		},
+	}
+	for _, tt := range tests {
+		u, err := bus.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rom := make([]byte, 0x8000)
+		copy(rom, tt.code)
+		if err = u.Attach(memory.NewROM(rom, 0x8000), "rom", 0x00_8000, 0x00_FFFF); err != nil {
+			t.Fatal(err)
+		}
+		if err = u.Attach(memory.NewRAM(make([]byte, 0x10000), 0x7E0000), "wram", 0x7E_0000, 0x7E_FFFF); err != nil {
+			t.Fatal(err)
+		}
+
+		c, _ := New(u)
+		c.RK, c.PC = 0x00, 0x8000
+		c.M, c.X = tt.m, tt.x
+		var got []testAccess
+		c.OnAccess = func(ea uint32, value byte, kind AccessKind) {
+			got = append(got, testAccess{ea, value, kind})
+		}
+		c.Step()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: accesses = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
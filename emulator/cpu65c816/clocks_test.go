@@ -0,0 +1,67 @@
+package cpu65c816
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes/emulator/bus"
+	"github.com/alttpo/snes/emulator/memory"
+)
+
+func TestCPU_Clocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		pc      uint32
+		fastROM bool
+		code    []byte
+		want    uint64
+	}{
+		// opcode fetch + internal cycle:
+		{"NOP", 0x00_8000, false, []byte{0xEA}, 8 + 6},
+		{"NOP FastROM", 0x80_8000, true, []byte{0xEA}, 6 + 6},
+		{"NOP FastROM disabled", 0x80_8000, false, []byte{0xEA}, 8 + 6},
+		// 3 ROM fetches + 2 data reads with 16-bit A:
+		{"LDA WRAM", 0x00_8000, false, []byte{0xAD, 0x00, 0x00}, 3*8 + 2*8},
+		{"LDA WRAM FastROM", 0x80_8000, true, []byte{0xAD, 0x00, 0x00}, 3*6 + 2*8},
+		{"LDA PPU", 0x00_8000, false, []byte{0xAD, 0x00, 0x21}, 3*8 + 2*6},
+		{"LDA joypad", 0x00_8000, false, []byte{0xAD, 0x16, 0x40}, 3*8 + 2*12},
+		{"LDA CPU", 0x00_8000, false, []byte{0xAD, 0x00, 0x42}, 3*8 + 2*6},
+		// 4 ROM fetches + 2 data writes:
+		{"STA long", 0x00_8000, false, []byte{0x8F, 0x00, 0x00, 0x7E}, 4*8 + 2*8},
+		// opcode fetch + internal cycle + 2 pushes:
+		{"PHA", 0x00_8000, false, []byte{0x48}, 8 + 6 + 2*8},
+	}
+	for _, tt := range tests {
+		u, err := bus.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rom := make([]byte, 0x8000)
+		copy(rom, tt.code)
+		for _, bank := range []uint32{0x00_0000, 0x80_0000} {
+			if err = u.Attach(memory.NewROM(rom, bank|0x8000), "rom", bank|0x8000, bank|0xFFFF); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = u.Attach(memory.NewRAM(make([]byte, 0x2000), 0), "wram", 0x00_0000, 0x00_1FFF); err != nil {
+			t.Fatal(err)
+		}
+		if err = u.Attach(memory.NewRAM(make([]byte, 0x4000), 0x2000), "io", 0x00_2000, 0x00_5FFF); err != nil {
+			t.Fatal(err)
+		}
+		if err = u.Attach(memory.NewRAM(make([]byte, 0x10000), 0x7E0000), "wram", 0x7E_0000, 0x7E_FFFF); err != nil {
+			t.Fatal(err)
+		}
+
+		c, _ := New(u)
+		c.SP = 0x1FFF
+		c.FastROM = tt.fastROM
+		c.RK, c.PC = byte(tt.pc>>16), uint16(tt.pc)
+		c.Step()
+		if c.Clocks != tt.want {
+			t.Errorf("%s: %d clocks, want %d", tt.name, c.Clocks, tt.want)
+		}
+		if c.AllClocks != tt.want {
+			t.Errorf("%s: AllClocks = %d, want %d", tt.name, c.AllClocks, tt.want)
+		}
+	}
+}
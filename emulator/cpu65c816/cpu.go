@@ -11,6 +11,7 @@ import (
 	"log"
 
 	"github.com/alttpo/snes/emulator/bus"
+	"github.com/alttpo/snes/timing"
 )
 
 type instructionType struct {
@@ -330,6 +331,28 @@ var instructions = [256]instructionType{
 	{0xff, "sbc", m_Absolute_Long_X, 4, 6, op_sbc},           // SBC $FEDCBA,X
 }
 
+// AccessKind classifies a CPU bus access
+type AccessKind uint8
+
+const (
+	AccessFetch AccessKind = iota // opcode and operand bytes of the current instruction
+	AccessRead
+	AccessWrite
+)
+
+func (k AccessKind) String() string {
+	switch k {
+	case AccessFetch:
+		return "fetch"
+	case AccessRead:
+		return "read"
+	case AccessWrite:
+		return "write"
+	default:
+		return "unknown"
+	}
+}
+
 type CPU struct {
 	Bus *bus.Bus
 
@@ -337,9 +360,13 @@ type CPU struct {
 
 	// additional emulator variables
 	AllCycles uint64 // total number of cycles of CPU instance
+	AllClocks uint64 // total master clocks elapsed
 	Cycles    byte   // number of cycles for this step
+	Clocks    uint64 // number of master clocks for this step
+	FastROM   bool   // MEMSEL ($420D) bit 0; makes ROM accesses in banks $80-$FF take 6 master clocks instead of 8
 	stepPC    uint16 // how many bytes should PC be increased in this step?
 	Stopped   bool   // set after STP instruction is executed
+	Waiting   bool   // set after WAI instruction is executed until an interrupt arrives
 
 	// previous register's value exists for debugging purposes
 	PRK byte   // previous value of program banK register
@@ -349,6 +376,9 @@ type CPU struct {
 	OnWDM func(wdm byte)
 	OnPC  map[uint32]func()
 
+	// OnAccess is called after each bus access the CPU makes
+	OnAccess func(ea uint32, value byte, kind AccessKind)
+
 	// 65c816 registers
 	PC uint16 // Program Counter
 	SP uint16 // Stack Pointer
@@ -379,6 +409,9 @@ type CPU struct {
 	E byte // Emulation mode flag
 
 	Interrupt byte // interrupt type to perform
+
+	accessClocks uint64 // master clocks of the bus accesses in this step
+	accesses     byte   // number of bus accesses in this step
 }
 
 func New(bus *bus.Bus) (*CPU, error) {
@@ -451,8 +484,70 @@ func (cpu *CPU) compare16(a, b uint16) {
 
 // ----------------------------------------------------------------
 
+// charge adds the master clocks of a bus access to the current step
+func (cpu *CPU) charge(ea uint32) {
+	cpu.accessClocks += timing.AccessClocks(ea, cpu.FastROM)
+	cpu.accesses++
+}
+
+// read reads a byte from the bus, charging the access time
+func (cpu *CPU) read(ea uint32) byte {
+	cpu.charge(ea)
+	value := cpu.Bus.EaRead(ea)
+	if cpu.OnAccess != nil {
+		cpu.OnAccess(ea, value, AccessRead)
+	}
+	return value
+}
+
+// operand reads an operand byte of the current instruction from the program bank, reported as a fetch
+func (cpu *CPU) operand(addr uint16) byte {
+	ea := uint32(cpu.RK)<<16 | uint32(addr)
+	cpu.charge(ea)
+	value := cpu.Bus.EaRead(ea)
+	if cpu.OnAccess != nil {
+		cpu.OnAccess(ea, value, AccessFetch)
+	}
+	return value
+}
+
+func (cpu *CPU) operand16(addr uint16) uint16 {
+	ll := cpu.operand(addr)
+	hh := cpu.operand(addr + 1)
+	return uint16(hh)<<8 | uint16(ll)
+}
+
+func (cpu *CPU) operand24(addr uint16) uint32 {
+	if cpu.OnAccess != nil {
+		ll := cpu.operand(addr)
+		mm := cpu.operand(addr + 1)
+		hh := cpu.operand(addr + 2)
+		return uint32(hh)<<16 | uint32(mm)<<8 | uint32(ll)
+	}
+	return cpu.nRead24_wrap(cpu.RK, addr)
+}
+
+// fetch reads an opcode from the bus, charging the access time
+func (cpu *CPU) fetch(ea uint32) byte {
+	cpu.charge(ea)
+	value := cpu.Bus.EaFetch(ea)
+	if cpu.OnAccess != nil {
+		cpu.OnAccess(ea, value, AccessFetch)
+	}
+	return value
+}
+
+// write writes a byte to the bus, charging the access time
+func (cpu *CPU) write(ea uint32, value byte) {
+	cpu.charge(ea)
+	cpu.Bus.EaWrite(ea, value)
+	if cpu.OnAccess != nil {
+		cpu.OnAccess(ea, value, AccessWrite)
+	}
+}
+
 func (cpu *CPU) nWrite(bank byte, addr uint16, value byte) {
-	cpu.Bus.EaWrite(uint32(bank)<<16|uint32(addr), value)
+	cpu.write(uint32(bank)<<16|uint32(addr), value)
 }
 
 // probably not needed...
@@ -460,26 +555,26 @@ func (cpu *CPU) nWrite16_wrap(bank byte, addr uint16, value uint16) {
 	bank32 := uint32(bank) << 16
 	ll := byte(value)
 	hh := byte(value >> 8)
-	cpu.Bus.EaWrite(bank32|uint32(addr), ll)
-	cpu.Bus.EaWrite(bank32|uint32(addr+1), hh)
+	cpu.write(bank32|uint32(addr), ll)
+	cpu.write(bank32|uint32(addr+1), hh)
 }
 
 func (cpu *CPU) nWrite16_cross(bank byte, addr uint16, value uint16) {
 	ea := uint32(bank)<<16 | uint32(addr)
 	ll := byte(value)
 	hh := byte(value >> 8)
-	cpu.Bus.EaWrite(ea, ll)
-	cpu.Bus.EaWrite(ea+1, hh)
+	cpu.write(ea, ll)
+	cpu.write(ea+1, hh)
 }
 
 func (cpu *CPU) nRead(bank byte, addr uint16) byte {
-	return cpu.Bus.EaRead(uint32(bank)<<16 | uint32(addr))
+	return cpu.read(uint32(bank)<<16 | uint32(addr))
 }
 
 func (cpu *CPU) nRead16_wrap(bank byte, addr uint16) uint16 {
 	bank32 := uint32(bank) << 16
-	ll := cpu.Bus.EaRead(bank32 | uint32(addr))
-	hh := cpu.Bus.EaRead(bank32 | uint32(addr+1))
+	ll := cpu.read(bank32 | uint32(addr))
+	hh := cpu.read(bank32 | uint32(addr+1))
 	return uint16(hh)<<8 | uint16(ll)
 }
 
@@ -492,13 +587,23 @@ func (cpu *CPU) nRead16_wrap(bank byte, addr uint16) uint16 {
 //}
 
 func (cpu *CPU) nRead24_wrap(bank byte, addr uint16) uint32 {
+	bank32 := uint32(bank) << 16
+	if cpu.OnAccess != nil {
+		ll := cpu.read(bank32 | uint32(addr))
+		mm := cpu.read(bank32 | uint32(addr+1))
+		hh := cpu.read(bank32 | uint32(addr+2))
+		return uint32(hh)<<16 | uint32(mm)<<8 | uint32(ll)
+	}
+	cpu.charge(bank32 | uint32(addr))
+	cpu.charge(bank32 | uint32(addr+1))
+	cpu.charge(bank32 | uint32(addr+2))
 	return cpu.Bus.EaRead24_wrap(bank, addr)
 }
 
 func (cpu *CPU) nRead16_cross(bank byte, addr uint16) uint16 {
 	ea := uint32(bank)<<16 | uint32(addr)
-	ll := cpu.Bus.EaRead(ea)
-	hh := cpu.Bus.EaRead((ea + 1) & 0x00ffffff) // wrap on 24bits
+	ll := cpu.read(ea)
+	hh := cpu.read((ea + 1) & 0x00ffffff) // wrap on 24bits
 	return uint16(hh)<<8 | uint16(ll)
 }
 
@@ -510,10 +615,10 @@ func (cpu *CPU) cmdRead() byte {
 
 	case m_Immediate, m_Immediate_flagM, m_Immediate_flagX:
 		//return cpu.Bus.EaRead(uint32(cpu.RK) << 16 | uint32(cpu.StepInfo.Addr)) // Addr=PC+1
-		return cpu.nRead(cpu.RK, cpu.StepInfo.Addr)
+		return cpu.operand(cpu.StepInfo.Addr)
 
 	case m_DP, m_DP_X, m_DP_Y, m_Stack_Relative:
-		return cpu.Bus.EaRead(uint32(cpu.StepInfo.Addr)) // cpu.StepInfo.Addr is uint16
+		return cpu.read(uint32(cpu.StepInfo.Addr)) // cpu.StepInfo.Addr is uint16
 
 	case m_DP_Indirect_Long,
 		m_DP_Indirect_Long_Y,
@@ -522,7 +627,7 @@ func (cpu *CPU) cmdRead() byte {
 		m_Absolute_X,
 		m_Absolute_Y,
 		m_Stack_Relative_Indirect_Y:
-		return cpu.Bus.EaRead(cpu.StepInfo.EA)
+		return cpu.read(cpu.StepInfo.EA)
 
 	case m_Absolute,
 		m_DP_X_Indirect,
@@ -546,7 +651,7 @@ func (cpu *CPU) cmdRead16() uint16 {
 		return cpu.RA
 
 	case m_Immediate, m_Immediate_flagM, m_Immediate_flagX:
-		return cpu.nRead16_wrap(cpu.RK, cpu.StepInfo.Addr)
+		return cpu.operand16(cpu.StepInfo.Addr)
 
 	case m_DP, m_DP_X, m_DP_Y, m_Stack_Relative:
 		return cpu.nRead16_wrap(0x00, cpu.StepInfo.Addr)
@@ -559,8 +664,8 @@ func (cpu *CPU) cmdRead16() uint16 {
 		m_Absolute_Y,
 		m_Absolute_X_Indirect,
 		m_Stack_Relative_Indirect_Y:
-		ll := cpu.Bus.EaRead(cpu.StepInfo.EA) // todo - zastapic to jakos?
-		hh := cpu.Bus.EaRead(cpu.StepInfo.EA + 1)
+		ll := cpu.read(cpu.StepInfo.EA) // todo - zastapic to jakos?
+		hh := cpu.read(cpu.StepInfo.EA + 1)
 		return uint16(hh)<<8 | uint16(ll)
 
 	case m_Absolute,
@@ -581,7 +686,7 @@ func (cpu *CPU) cmdWrite(value byte) {
 	switch cpu.StepInfo.Mode {
 
 	case m_DP, m_DP_X, m_DP_Y, m_Stack_Relative:
-		cpu.Bus.EaWrite(uint32(cpu.StepInfo.Addr), value) // StepInfo.Addr is uint16
+		cpu.write(uint32(cpu.StepInfo.Addr), value) // StepInfo.Addr is uint16
 
 	case m_DP_Indirect_Long,
 		m_DP_Indirect_Long_Y,
@@ -590,7 +695,7 @@ func (cpu *CPU) cmdWrite(value byte) {
 		m_Absolute_X,
 		m_Absolute_Y,
 		m_Stack_Relative_Indirect_Y:
-		cpu.Bus.EaWrite(cpu.StepInfo.EA, value)
+		cpu.write(cpu.StepInfo.EA, value)
 
 	case m_Absolute,
 		m_DP_X_Indirect,
@@ -620,8 +725,8 @@ func (cpu *CPU) cmdWrite16(value uint16) {
 		m_Stack_Relative_Indirect_Y:
 		ll := byte(value)
 		hh := byte(value >> 8)
-		cpu.Bus.EaWrite(cpu.StepInfo.EA, ll)
-		cpu.Bus.EaWrite(cpu.StepInfo.EA+1, hh)
+		cpu.write(cpu.StepInfo.EA, ll)
+		cpu.write(cpu.StepInfo.EA+1, hh)
 
 	case m_Absolute,
 		m_DP_X_Indirect,
@@ -791,16 +896,24 @@ func (cpu *CPU) setZN16(value uint16) {
 	cpu.setN16(value)
 }
 
-// triggerNMI causes a non-maskable interrupt to occur on the next cycle
-func (cpu *CPU) triggerNMI() {
+// TriggerNMI causes a non-maskable interrupt to occur on the next cycle
+func (cpu *CPU) TriggerNMI() {
 	cpu.Interrupt = interruptNMI
+	cpu.Waiting = false
+}
+
+// InterruptPending reports whether the next Step enters an interrupt handler instead of executing an instruction
+func (cpu *CPU) InterruptPending() bool {
+	return cpu.Interrupt == interruptNMI || cpu.Interrupt == interruptIRQ
 }
 
-// triggerIRQ causes an IRQ interrupt to occur on the next cycle
+// TriggerIRQ causes an IRQ interrupt to occur on the next cycle; a pending NMI takes precedence. WAI resumes even
+// when IRQs are disabled.
 func (cpu *CPU) TriggerIRQ() {
-	if cpu.I == 0 {
+	if cpu.I == 0 && cpu.Interrupt != interruptNMI {
 		cpu.Interrupt = interruptIRQ
 	}
+	cpu.Waiting = false
 }
 
 /* ====================================================================
@@ -815,9 +928,12 @@ type StepInfo struct {
 	EA   uint32 // effective addres    - 24 bit in 65c816
 	Addr uint16 // address within bank - used in place of EA in some modes
 	Mode byte
+
+	Opcode byte
 }
 
-// Step executes a single CPU instruction
+// Step executes a single CPU instruction. A pending interrupt is entered in a step of its own, so the next step runs
+// the handler's first instruction; while waiting in WAI a step idles for one cycle.
 func (cpu *CPU) Step() (int, bool) {
 
 	//if cpu.stall > 0 {
@@ -827,21 +943,35 @@ func (cpu *CPU) Step() (int, bool) {
 
 	//cycles := cpu.Cycles
 
-	if cb, ok := cpu.OnPC[uint32(cpu.RK)<<16|uint32(cpu.PC)]; ok {
-		cb()
+	cpu.accessClocks, cpu.accesses = 0, 0
+
+	if cpu.InterruptPending() {
+		if cpu.Interrupt == interruptNMI {
+			cpu.Cycles = cpu.nmi()
+		} else {
+			cpu.Cycles = cpu.irq()
+		}
+		cpu.Interrupt = interruptNone
+		cpu.AllCycles += uint64(cpu.Cycles)
+		cpu.endStep()
+		return int(cpu.Cycles), false
 	}
 
-	switch cpu.Interrupt {
-	case interruptNMI:
-		cpu.nmi()
-	case interruptIRQ:
-		cpu.irq()
+	if cpu.Waiting {
+		// idle until an interrupt:
+		cpu.Cycles = 1
+		cpu.AllCycles++
+		cpu.endStep()
+		return 1, false
+	}
+
+	if cb, ok := cpu.OnPC[uint32(cpu.RK)<<16|uint32(cpu.PC)]; ok {
+		cb()
 	}
-	cpu.Interrupt = interruptNone
 
 	cpu.PPC = cpu.PC
 	cpu.PRK = cpu.RK
-	opcode := cpu.nRead(cpu.RK, cpu.PC)
+	opcode := cpu.fetch(uint32(cpu.RK)<<16 | uint32(cpu.PC))
 	mode := instructions[opcode].mode
 	cpu.stepPC = uint16(instructions[opcode].size)
 	cpu.Cycles = instructions[opcode].cycles
@@ -858,11 +988,11 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// $9876          - p. 288 or 5.2
 	case m_Absolute:
-		addr = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		addr = cpu.operand16(cpu.PC + 1)
 
 	// $9876, X       - p. 289 or 5.3
 	case m_Absolute_X:
-		arg16 = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		arg16 = cpu.operand16(cpu.PC + 1)
 		if cpu.X == 1 {
 			ea = (uint32(cpu.RDBR)<<16 | uint32(arg16)) + uint32(cpu.RXl)
 			pageCrossed = pagesDiffer(arg16, arg16+uint16(cpu.RXl))
@@ -874,7 +1004,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// $9876, Y       - p. 290 or 5.3
 	case m_Absolute_Y:
-		arg16 = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		arg16 = cpu.operand16(cpu.PC + 1)
 		if cpu.X == 1 {
 			ea = (uint32(cpu.RDBR)<<16 | uint32(arg16)) + uint32(cpu.RYl)
 			pageCrossed = pagesDiffer(arg16, arg16+uint16(cpu.RYl))
@@ -910,12 +1040,12 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// $12            - p. 298 or 5.7
 	case m_DP:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		addr = uint16(arg8) + cpu.RD
 
 	// $12, X         - p. 299 or 5.8
 	case m_DP_X:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		if cpu.X == 1 {
 			addr = uint16(arg8) + uint16(cpu.RXl) + cpu.RD
 		} else {
@@ -924,7 +1054,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// $12, Y         - p. 300 or 5.8
 	case m_DP_Y:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		if cpu.X == 1 {
 			addr = uint16(arg8) + uint16(cpu.RYl) + cpu.RD
 		} else {
@@ -933,7 +1063,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// ($12, X)       - p. 301 or 5.11
 	case m_DP_X_Indirect:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		if cpu.X == 1 {
 			addr = cpu.nRead16_wrap(0x00, uint16(arg8)+uint16(cpu.RXl)+cpu.RD)
 		} else {
@@ -943,18 +1073,18 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// ($12)          - p. 302 or 5.9
 	case m_DP_Indirect:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		addr = cpu.nRead16_wrap(0x00, uint16(arg8)+cpu.RD)
 
 	// [$12]          - p. 303 or 5.10
 	case m_DP_Indirect_Long:
 		// address = cpu.read16bug(uint16(cpu.Read(cpu.PC+1) + cpu.RX))
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		ea = cpu.nRead24_wrap(0x00, uint16(arg8)+cpu.RD)
 
 	// ($12), Y       - p. 304 or 5.12
 	case m_DP_Indirect_Y:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		if cpu.X == 1 {
 			addr = cpu.nRead16_wrap(0, uint16(arg8)+cpu.RD) + uint16(cpu.RYl)
 			pageCrossed = pagesDiffer(addr-uint16(cpu.RYl), addr)
@@ -965,7 +1095,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// [$12], Y       - p. 305 or 5.13
 	case m_DP_Indirect_Long_Y:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		ea = cpu.nRead24_wrap(0x00, uint16(arg8)+cpu.RD)
 		if cpu.X == 1 {
 			ea = ea + uint32(cpu.RYl)
@@ -975,7 +1105,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// ($1234, X)     - p. 291 or 5.5
 	case m_Absolute_X_Indirect:
-		arg16 = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		arg16 = cpu.operand16(cpu.PC + 1)
 		if cpu.X == 1 {
 			arg16 = arg16 + uint16(cpu.RXl)
 		} else {
@@ -987,23 +1117,23 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// ($1234)        - p. 292 or 5.4
 	case m_Absolute_Indirect:
-		addr = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		addr = cpu.operand16(cpu.PC + 1)
 		//addr   = cpu.nRead16_wrap(0x00,   arg16)
 
 	// [$1234]        - p. 293 or 5.10
 	case m_Absolute_Indirect_Long:
-		addr = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		addr = cpu.operand16(cpu.PC + 1)
 		//EA     = cpu.nRead24_wrap(0x00, arg16)
 		//fmt.Fprintf(&cpu.LogBuf, "m_Absolute_Indirect_Long: arg16 $%04x EA $%06x\n", arg16, EA)
 
 	// $abcdef        - p. 294 or 5.16
 	case m_Absolute_Long:
-		ea = cpu.nRead24_wrap(cpu.RK, cpu.PC+1)
+		ea = cpu.operand24(cpu.PC + 1)
 		//fmt.Fprintf(&cpu.LogBuf, "m_Absolute_Long: EA $%06x\n", EA)
 
 	// $abcdex, X     - p. 295 or 5.17
 	case m_Absolute_Long_X:
-		ea = cpu.nRead24_wrap(cpu.RK, cpu.PC+1)
+		ea = cpu.operand24(cpu.PC + 1)
 		if cpu.X == 1 {
 			ea = ea + uint32(cpu.RXl)
 		} else {
@@ -1013,7 +1143,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// rel8           - p. 308 or 5.18 (BRA)
 	case m_PC_Relative:
-		arg16 = uint16(cpu.nRead(cpu.RK, cpu.PC+1))
+		arg16 = uint16(cpu.operand(cpu.PC + 1))
 		if arg16 < 0x80 {
 			addr = cpu.PC + 2 + arg16
 		} else {
@@ -1022,7 +1152,7 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// rel16          - p. 309 or 5.18 (BRL)
 	case m_PC_Relative_Long:
-		arg16 = cpu.nRead16_wrap(cpu.RK, cpu.PC+1)
+		arg16 = cpu.operand16(cpu.PC + 1)
 		addr = cpu.PC + 3 + arg16
 		//if arg16 < 0x8000 {
 		//	addr = cpu.PC + 3 + arg16
@@ -1032,12 +1162,12 @@ func (cpu *CPU) Step() (int, bool) {
 
 	// $32, S         - p. 324 or 5.20
 	case m_Stack_Relative:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		addr = uint16(arg8) + cpu.SP
 
 	// ($32, S), Y    - p. 325 or 5.21 (STACK,S),Y
 	case m_Stack_Relative_Indirect_Y:
-		arg8 = cpu.nRead(cpu.RK, cpu.PC+1)
+		arg8 = cpu.operand(cpu.PC + 1)
 		arg16 = cpu.nRead16_wrap(0x00, uint16(arg8)+cpu.SP)
 		//fmt.Fprintf(&cpu.LogBuf, "m_Stack_Relative_Indirect_Y: arg16 $%04x ", arg16)
 		if cpu.X == 1 {
@@ -1076,11 +1206,12 @@ func (cpu *CPU) Step() (int, bool) {
 	}
 
 	// instruction execution
-	cpu.StepInfo = StepInfo{ea, addr, mode}
+	cpu.StepInfo = StepInfo{ea, addr, mode, opcode}
 	instructions[opcode].proc(cpu)
 
 	// counter and PC update
 	cpu.AllCycles += uint64(cpu.Cycles)
+	cpu.endStep()
 	cpu.PC += cpu.stepPC
 	if cpu.Stopped {
 		return int(cpu.Cycles), true
@@ -1088,30 +1219,49 @@ func (cpu *CPU) Step() (int, bool) {
 	return int(cpu.Cycles), false
 }
 
+// endStep converts the step's cycles to master clocks: each bus access takes the time of the region it touched and
+// the remaining cycles are internal operations of 6 master clocks
+func (cpu *CPU) endStep() {
+	cpu.Clocks = cpu.accessClocks
+	if cpu.Cycles > cpu.accesses {
+		cpu.Clocks += uint64(cpu.Cycles-cpu.accesses) * timing.FastClocks
+	}
+	cpu.AllClocks += cpu.Clocks
+}
+
 // NMI - Non-Maskable Interrupt
-// XXXX - valid for 6502
-func (cpu *CPU) nmi() {
-	cpu.push16(cpu.PC)
-	op_php(cpu)
-	//cpu.PC = cpu.Read16(0xFFFA)
-	cpu.PC = cpu.nRead16_cross(0x00, 0xFFEA)
-	cpu.I = 1
-	cpu.Cycles += 7
+func (cpu *CPU) nmi() byte {
+	return cpu.interrupt(0xFFEA, 0xFFFA)
 }
 
 // IRQ - IRQ Interrupt
-// XXXX - valid for 6502
-func (cpu *CPU) irq() {
+func (cpu *CPU) irq() byte {
+	return cpu.interrupt(0xFFEE, 0xFFFE)
+}
+
+// interrupt enters a hardware interrupt handler through the native or emulation mode vector and returns its cycles
+func (cpu *CPU) interrupt(native, emulation uint16) byte {
+	cpu.Waiting = false
+	if cpu.E == 1 {
+		cpu.push16(cpu.PC) // "next instruction to be executed" means current pc because irq is processed at start of new comm.
+		cpu.push(cpu.Flags() &^ 0x10)
+
+		cpu.I = 1
+		cpu.D = 0
+		cpu.RK = 0
+		cpu.PC = cpu.nRead16_cross(0x00, emulation)
+		return 7
+	}
 
 	cpu.push(cpu.RK)
-	cpu.push16(cpu.PC) // "next instruction to be executed" means current pc because irq is processed at start of new comm.
+	cpu.push16(cpu.PC)
 	cpu.push(cpu.Flags())
 
 	cpu.I = 1
 	cpu.D = 0
 	cpu.RK = 0
-	cpu.PC = cpu.nRead16_cross(0x00, 0xFFEE)
-	//log.Println(fmt.Sprintf("\ncpu: irq triggered, PC %4X", cpu.PC))
+	cpu.PC = cpu.nRead16_cross(0x00, native)
+	return 8
 }
 
 /* ====================================================================
@@ -2089,8 +2239,8 @@ accumulator means six bytes will be moved.
 
 */
 func op_mvn(cpu *CPU) {
-	dst := cpu.nRead(cpu.RK, cpu.StepInfo.Addr)
-	src := cpu.nRead(cpu.RK, cpu.StepInfo.Addr+1)
+	dst := cpu.operand(cpu.StepInfo.Addr)
+	src := cpu.operand(cpu.StepInfo.Addr + 1)
 
 	cpu.RDBR = dst
 	if cpu.X == 1 {
@@ -2113,8 +2263,8 @@ func op_mvn(cpu *CPU) {
 
 // MVP - MoVe memory Positive
 func op_mvp(cpu *CPU) {
-	dst := cpu.nRead(cpu.RK, cpu.StepInfo.Addr)
-	src := cpu.nRead(cpu.RK, cpu.StepInfo.Addr+1)
+	dst := cpu.operand(cpu.StepInfo.Addr)
+	src := cpu.operand(cpu.StepInfo.Addr + 1)
 
 	cpu.RDBR = dst
 	if cpu.X == 1 {
@@ -2203,7 +2353,7 @@ func op_plb(cpu *CPU) {
 
 // REset Processor status bits
 func op_rep(cpu *CPU) {
-	neg_flags := ^cpu.Bus.EaRead(cpu.StepInfo.EA)
+	neg_flags := ^cpu.operand(cpu.StepInfo.Addr)
 	tmp_flags := cpu.Flags() & neg_flags
 	//fmt.Fprintf(&cpu.LogBuf, "op_rep %08b %08b %08b %08b\n", cpu.Bus.EaRead(cpu.StepInfo.EA), neg_flags, cpu.Flags(), tmp_flags)
 	cpu.SetFlags(tmp_flags)
@@ -2211,7 +2361,7 @@ func op_rep(cpu *CPU) {
 
 // SEt Processor status bits
 func op_sep(cpu *CPU) {
-	tmp_flags := cpu.Flags() | cpu.Bus.EaRead(cpu.StepInfo.EA)
+	tmp_flags := cpu.Flags() | cpu.operand(cpu.StepInfo.Addr)
 	cpu.SetFlags(tmp_flags)
 }
 
@@ -2327,6 +2477,7 @@ func op_tyx(cpu *CPU) {
 
 // WAI - WAit for Interrupt
 func op_wai(cpu *CPU) {
+	cpu.Waiting = true
 }
 
 // WDM - William D. Mensch, Jr.
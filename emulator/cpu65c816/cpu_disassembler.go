@@ -16,6 +16,15 @@ func appendCPUFlags(xb *xbuf.B, flag byte, name byte) {
 	}
 }
 
+// peek reads a byte of the program bank through EaDump, so the read is not charged to the step or reported to
+// OnAccess or bus observers
+func (c *CPU) peek(addr uint16) byte {
+	var b [1]byte
+	ea := uint32(c.RK)<<16 | uint32(addr)
+	c.Bus.EaDump(ea, ea, b[:])
+	return b[0]
+}
+
 func (c *CPU) DisassembleCurrentPC(o []byte) []byte {
 	//fmt.Fprintf(w, "\n%s", c.Disassemble(c.PC))
 	return c.DisassembleTo(c.PC, o)
@@ -27,64 +36,54 @@ func (c *CPU) DisassembleTo(myPC uint16, o []byte) []byte {
 	//var myPC uint16 = c.PC
 
 	//opcode := c.Read(myPC)
-	opcode := c.nRead(c.RK, myPC)
+	opcode := c.peek(myPC)
 	mode := instructions[opcode].mode
-
-	// crude and incosistent size adjust
-	var sizeAdjust byte
-	if mode == m_Immediate_flagM {
-		sizeAdjust = c.M
-	}
-	if mode == m_Immediate_flagX {
-		sizeAdjust = c.X
-	}
-
-	bytes := instructions[opcode].size - sizeAdjust
+	bytes := InstructionSize(opcode, c.M, c.X)
 	name := instructions[opcode].name
 
 	xb.Db(c.Cycles).C('\t').X02(c.RK).C(':').X04(myPC).C('|')
 
 	switch bytes {
 	case 4:
-		w0 := c.nRead(c.RK, myPC+0)
-		w1 := c.nRead(c.RK, myPC+1)
-		w2 := c.nRead(c.RK, myPC+2)
-		w3 := c.nRead(c.RK, myPC+3)
+		w0 := c.peek(myPC + 0)
+		w1 := c.peek(myPC + 1)
+		w2 := c.peek(myPC + 2)
+		w3 := c.peek(myPC + 3)
 		//o = fmt.Appendf(o, "%d\t%02x:%04x│%02x %02x %02x %02x│%3s ",
 		//	c.Cycles, c.RK, myPC, w0, w1, w2, w3, name)
 		xb.X02(w0).C(' ').X02(w1).C(' ').X02(w2).C(' ').X02(w3)
 		xb.C('|').Sn(name, 3).C(' ')
-		c.formatInstructionModeTo(&xb, mode, w0, w1, w2, w3)
+		formatOperandTo(&xb, mode, myPC, c.M, c.X, w0, w1, w2, w3)
 	case 3:
-		w0 := c.nRead(c.RK, myPC+0)
-		w1 := c.nRead(c.RK, myPC+1)
-		w2 := c.nRead(c.RK, myPC+2)
+		w0 := c.peek(myPC + 0)
+		w1 := c.peek(myPC + 1)
+		w2 := c.peek(myPC + 2)
 		//o = fmt.Appendf(o, "%d\t%02x:%04x│%02x %02x %02x   │%3s ",
 		//	c.Cycles, c.RK, myPC, w0, w1, w2, name)
 		xb.X02(w0).C(' ').X02(w1).C(' ').X02(w2).S("   ")
 		xb.C('|').Sn(name, 3).C(' ')
-		c.formatInstructionModeTo(&xb, mode, w0, w1, w2, 0)
+		formatOperandTo(&xb, mode, myPC, c.M, c.X, w0, w1, w2, 0)
 	case 2:
-		w0 := c.nRead(c.RK, myPC+0)
-		w1 := c.nRead(c.RK, myPC+1)
+		w0 := c.peek(myPC + 0)
+		w1 := c.peek(myPC + 1)
 		//o = fmt.Appendf(o, "%d\t%02x:%04x│%02x %02x      │%3s ",
 		//	c.Cycles, c.RK, myPC, w0, w1, name)
 		xb.X02(w0).C(' ').X02(w1).S("      ")
 		xb.C('|').Sn(name, 3).C(' ')
-		c.formatInstructionModeTo(&xb, mode, w0, w1, 0, 0)
+		formatOperandTo(&xb, mode, myPC, c.M, c.X, w0, w1, 0, 0)
 	case 1:
-		w0 := c.nRead(c.RK, myPC+0)
+		w0 := c.peek(myPC + 0)
 		//o = fmt.Appendf(o, "%d\t%02x:%04x│%02x         │%3s ",
 		//	c.Cycles, c.RK, myPC, w0, name)
 		xb.X02(w0).S("         ")
 		xb.C('|').Sn(name, 3).C(' ')
-		c.formatInstructionModeTo(&xb, mode, w0, 0, 0, 0)
+		formatOperandTo(&xb, mode, myPC, c.M, c.X, w0, 0, 0, 0)
 	default:
 		//o = fmt.Appendf(o, "%d\t%02x:%04x│err: cmd len %d│%3s ",
 		//	c.Cycles, c.RK, myPC, bytes, name)
 		xb.S("???        ")
 		xb.C('|').Sn(name, 3).C(' ')
-		c.formatInstructionModeTo(&xb, mode, 0, 0, 0, 0)
+		formatOperandTo(&xb, mode, myPC, c.M, c.X, 0, 0, 0, 0)
 	}
 	xb.C('|')
 
@@ -122,7 +121,8 @@ func (c *CPU) DisassembleTo(myPC uint16, o []byte) []byte {
 
 var spaces = [13]byte{' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
 
-func (c *CPU) formatInstructionModeTo(xb *xbuf.B, mode byte, w0 byte, w1 byte, w2 byte, w3 byte) {
+// formatOperandTo appends the operand of the instruction bytes w0-w3 at pc, padded to 13 characters
+func formatOperandTo(xb *xbuf.B, mode byte, pc uint16, m, x byte, w0 byte, w1 byte, w2 byte, w3 byte) {
 	switch mode {
 	case m_Absolute: // $9876       - p. 288 or 5.2
 		//o = fmt.Appendf(o, "$%02x%02x", w2, w1)
@@ -145,7 +145,7 @@ func (c *CPU) formatInstructionModeTo(xb *xbuf.B, mode byte, w0 byte, w1 byte, w
 			xb.C('#').C('$').X02(w1).Sb(spaces[4:13])
 		}
 	case m_Immediate_flagM: // #$aaaa/$#aa - p. 306 or 5.14
-		if c.M == 1 {
+		if m == 1 {
 			//o = fmt.Appendf(o, "#$%02x", w1)
 			xb.C('#').C('$').X02(w1).Sb(spaces[4:13])
 		} else {
@@ -153,7 +153,7 @@ func (c *CPU) formatInstructionModeTo(xb *xbuf.B, mode byte, w0 byte, w1 byte, w
 			xb.C('#').C('$').X02(w2).X02(w1).Sb(spaces[6:13])
 		}
 	case m_Immediate_flagX: // #$aa        - p. 306 or 5.14 // XXX fix it
-		if c.X == 1 {
+		if x == 1 {
 			//o = fmt.Appendf(o, "#$%02x", w1)
 			xb.C('#').C('$').X02(w1).Sb(spaces[4:13])
 		} else {
@@ -206,18 +206,18 @@ func (c *CPU) formatInstructionModeTo(xb *xbuf.B, mode byte, w0 byte, w1 byte, w
 		xb.C('#').C('$').X02(w2).C(',').C('#').C('$').X02(w1).Sb(spaces[9:13])
 	case m_PC_Relative: // rel8        - p. 308 or 5.18 (BRA)
 		w216 := uint16(w1)
-		if w2 < 0x80 {
-			dest := c.PC + 2 + w216
+		if w1 < 0x80 {
+			dest := pc + 2 + w216
 			//o = fmt.Appendf(o, "$%02x ($%04x +)", w216, dest)
 			xb.C('$').X02(w1).S(" ($").X04(dest).S(" +)").Sb(spaces[13:13])
 		} else {
-			dest := c.PC + 2 + w216 - 0x100
+			dest := pc + 2 + w216 - 0x100
 			//o = fmt.Appendf(o, "$%02x ($%04x -)", w216, dest)
 			xb.C('$').X02(w1).S(" ($").X04(dest).S(" -)").Sb(spaces[13:13])
 		}
 	case m_PC_Relative_Long: // rel16       - p. 309 or 5.18 (BRL)
 		arg16 := uint16(w2)<<8 | uint16(w1)
-		addr := c.PC + 3 + arg16
+		addr := pc + 3 + arg16
 		//o = fmt.Appendf(o, "$%04x", addr)
 		xb.C('$').X04(addr).Sb(spaces[5:13])
 	case m_Stack_Relative: // $32, Sn      - p. 324 or 5.20
@@ -225,7 +225,7 @@ func (c *CPU) formatInstructionModeTo(xb *xbuf.B, mode byte, w0 byte, w1 byte, w
 		xb.C('$').X02(w1).S(", Sn").Sb(spaces[9:13])
 	case m_Stack_Relative_Indirect_Y: // ($32, Sn), Y - p. 325 or 5.21 (STACK,Sn),Y
 		//o = fmt.Appendf(o, "($%02x, Sn), Y", w1)
-		xb.C('$').C('(').X02(w1).S(", Sn), Y").Sb(spaces[12:13])
+		xb.C('(').C('$').X02(w1).S(", Sn), Y").Sb(spaces[12:13])
 	default:
 		//o = fmt.Appendf(o, "! unknown !")
 		xb.S("! unknown !").Sb(spaces[11:13])
@@ -0,0 +1,51 @@
+package cpu65c816
+
+import (
+	"strings"
+
+	"github.com/alttpo/snes/xbuf"
+)
+
+// InstructionSize returns the length in bytes of the instruction with the opcode under the M and X width flags
+func InstructionSize(opcode byte, m, x byte) int {
+	size := int(instructions[opcode].size)
+	switch instructions[opcode].mode {
+	case m_Immediate_flagM:
+		size -= int(m & 1)
+	case m_Immediate_flagX:
+		size -= int(x & 1)
+	}
+	return size
+}
+
+// Disassemble formats the instruction bytes op located at pc like DisassembleTo, e.g. "lda $0100, X". Operands
+// missing from op read as 0.
+func Disassemble(pc uint32, op []byte, m, x byte) string {
+	var w [4]byte
+	copy(w[:], op)
+	xb := xbuf.B(make([]byte, 0, 20))
+	xb.S(instructions[w[0]].name).C(' ')
+	formatOperandTo(&xb, instructions[w[0]].mode, uint16(pc), m, x, w[0], w[1], w[2], w[3])
+	return strings.TrimRight(string(xb), " ")
+}
+
+// EffectiveAddress returns the data address the last executed instruction's addressing mode resolved to; ok is false
+// for modes without one, such as immediate and implied
+func (cpu *CPU) EffectiveAddress() (ea uint32, ok bool) {
+	switch cpu.StepInfo.Mode {
+	case m_DP, m_DP_X, m_DP_Y, m_Stack_Relative:
+		return uint32(cpu.StepInfo.Addr), true
+	case m_Absolute:
+		// JMP and JSR targets are in the program bank:
+		if cpu.StepInfo.Opcode == 0x4C || cpu.StepInfo.Opcode == 0x20 {
+			return uint32(cpu.RK)<<16 | uint32(cpu.StepInfo.Addr), true
+		}
+		return uint32(cpu.RDBR)<<16 | uint32(cpu.StepInfo.Addr), true
+	case m_DP_X_Indirect, m_DP_Indirect, m_DP_Indirect_Y:
+		return uint32(cpu.RDBR)<<16 | uint32(cpu.StepInfo.Addr), true
+	case m_DP_Indirect_Long, m_DP_Indirect_Long_Y, m_Absolute_Long, m_Absolute_Long_X, m_Absolute_X, m_Absolute_Y,
+		m_Stack_Relative_Indirect_Y:
+		return cpu.StepInfo.EA & 0xFFFFFF, true
+	}
+	return 0, false
+}
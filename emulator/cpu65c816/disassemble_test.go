@@ -0,0 +1,73 @@
+package cpu65c816
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes/emulator/bus"
+	"github.com/alttpo/snes/emulator/memory"
+)
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		pc   uint32
+		op   []byte
+		m, x byte
+		want string
+		size int
+	}{
+		{0x008000, []byte{0xEA}, 1, 1, "nop", 1},
+		{0x008000, []byte{0xA9, 0x34, 0x12}, 0, 1, "lda #$1234", 3},
+		{0x008000, []byte{0xA9, 0x34, 0x12}, 1, 1, "lda #$34", 2},
+		{0x008000, []byte{0xA2, 0x34, 0x12}, 1, 0, "ldx #$1234", 3},
+		{0x008000, []byte{0xE2, 0x30}, 0, 0, "sep #$30", 2},
+		{0x008000, []byte{0xF4, 0x00, 0x80}, 1, 1, "pea #$8000", 3},
+		{0x008000, []byte{0xBD, 0x00, 0x01}, 1, 1, "lda $0100, X", 3},
+		{0x008000, []byte{0xAF, 0x00, 0x01, 0x7E}, 1, 1, "lda $7e0100", 4},
+		{0x008000, []byte{0xB7, 0x10}, 1, 1, "lda [$10], Y", 2},
+		{0x008000, []byte{0xB3, 0x03}, 1, 1, "lda ($03, Sn), Y", 2},
+		{0x008010, []byte{0xD0, 0xFB}, 1, 1, "bne $fb ($800d -)", 2},
+		{0x008010, []byte{0x80, 0x05}, 1, 1, "bra $05 ($8017 +)", 2},
+		{0x008010, []byte{0x82, 0x00, 0x10}, 1, 1, "brl $9013", 3},
+		{0x008000, []byte{0x54, 0x7E, 0x7F}, 1, 1, "mvn #$7f,#$7e", 3},
+		{0x008000, []byte{0x0A}, 1, 1, "asl A", 1},
+	}
+	for _, tt := range tests {
+		if got := Disassemble(tt.pc, tt.op, tt.m, tt.x); got != tt.want {
+			t.Errorf("Disassemble(% X) = %q, want %q", tt.op, got, tt.want)
+		}
+		if got := InstructionSize(tt.op[0], tt.m, tt.x); got != tt.size {
+			t.Errorf("InstructionSize($%02X) = %d, want %d", tt.op[0], got, tt.size)
+		}
+	}
+}
+
+func TestCPU_EffectiveAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		want uint32
+	}{
+		{"LDA abs uses the data bank", []byte{0xAD, 0x34, 0x12}, 0x7E_1234},
+		{"JMP abs uses the program bank", []byte{0x4C, 0x00, 0x90}, 0x80_9000},
+		{"JSR abs uses the program bank", []byte{0x20, 0x00, 0x90}, 0x80_9000},
+		{"LDA long", []byte{0xAF, 0x00, 0x01, 0x7F}, 0x7F_0100},
+	}
+	for _, tt := range tests {
+		u, err := bus.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rom := make([]byte, 0x8000)
+		copy(rom, tt.code)
+		if err = u.Attach(memory.NewROM(rom, 0x808000), "rom", 0x80_8000, 0x80_FFFF); err != nil {
+			t.Fatal(err)
+		}
+
+		c, _ := New(u)
+		c.RK, c.PC, c.RDBR = 0x80, 0x8000, 0x7E
+		c.Step()
+		if got, ok := c.EffectiveAddress(); !ok || got != tt.want {
+			t.Errorf("%s: EffectiveAddress() = $%06X, %v, want $%06X", tt.name, got, ok, tt.want)
+		}
+	}
+}
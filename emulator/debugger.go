@@ -0,0 +1,361 @@
+package emulator
+
+import (
+	"fmt"
+
+	"github.com/alttpo/snes/emulator/cpu65c816"
+	"github.com/alttpo/snes/mapping"
+	"github.com/alttpo/snes/mapping/util"
+)
+
+// BreakKind selects the accesses a breakpoint fires on; kinds may be combined
+type BreakKind uint8
+
+const (
+	BreakExecute BreakKind = 1 << iota
+	BreakRead
+	BreakWrite
+)
+
+func (k BreakKind) String() string {
+	s := ""
+	for _, n := range []struct {
+		k    BreakKind
+		name string
+	}{{BreakExecute, "x"}, {BreakRead, "r"}, {BreakWrite, "w"}} {
+		if k&n.k != 0 {
+			s += n.name
+		} else {
+			s += "-"
+		}
+	}
+	return s
+}
+
+// Register names a CPU register for breakpoint conditions
+type Register uint8
+
+const (
+	RegA Register = iota
+	RegX
+	RegY
+	RegS
+	RegD
+	RegDB
+	RegPB
+	RegP
+)
+
+// Value returns the register's current value, honoring the M and X width flags
+func (r Register) Value(cpu *cpu65c816.CPU) uint16 {
+	switch r {
+	case RegA:
+		if cpu.M == 1 {
+			return uint16(cpu.RAh)<<8 | uint16(cpu.RAl)
+		}
+		return cpu.RA
+	case RegX:
+		if cpu.X == 1 {
+			return uint16(cpu.RXl)
+		}
+		return cpu.RX
+	case RegY:
+		if cpu.X == 1 {
+			return uint16(cpu.RYl)
+		}
+		return cpu.RY
+	case RegS:
+		return cpu.SP
+	case RegD:
+		return cpu.RD
+	case RegDB:
+		return uint16(cpu.RDBR)
+	case RegPB:
+		return uint16(cpu.RK)
+	case RegP:
+		return uint16(cpu.Flags())
+	}
+	return 0
+}
+
+// Condition decides whether a breakpoint hit counts; it sees the CPU as of the access
+type Condition func(cpu *cpu65c816.CPU) bool
+
+// RegisterEquals is a Condition that the register holds value
+func RegisterEquals(r Register, value uint16) Condition {
+	return func(cpu *cpu65c816.CPU) bool { return r.Value(cpu) == value }
+}
+
+// RegisterMasked is a Condition that the register's bits under mask equal value, e.g. RegisterMasked(RegP, 0x20, 0)
+// for 16-bit A
+func RegisterMasked(r Register, mask, value uint16) Condition {
+	return func(cpu *cpu65c816.CPU) bool { return r.Value(cpu)&mask == value }
+}
+
+// All is a Condition that every condition holds
+func All(conditions ...Condition) Condition {
+	return func(cpu *cpu65c816.CPU) bool {
+		for _, c := range conditions {
+			if !c(cpu) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Breakpoint stops Run when the CPU executes, reads or writes a bus address in [Start, Start+Size) or any of its mirrors
+type Breakpoint struct {
+	ID    int
+	Kind  BreakKind
+	Start uint32
+	Size  uint32
+
+	// Condition, when set, must hold for a hit to count
+	Condition Condition
+	// HitCount is the hit to break on; hits before it are counted but do not break. 0 breaks on every hit.
+	HitCount uint64
+	// Hits counts the accesses that matched and met the condition
+	Hits uint64
+
+	Disabled bool
+
+	ranges []busRange
+}
+
+type busRange struct{ start, end uint32 }
+
+func (bp *Breakpoint) String() string {
+	return fmt.Sprintf("#%d %s $%06X-$%06X", bp.ID, bp.Kind, bp.Start, bp.Start+bp.Size-1)
+}
+
+func (bp *Breakpoint) contains(addr uint32) bool {
+	for _, r := range bp.ranges {
+		if addr >= r.start && addr < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// hit counts a matching access and reports whether it should break
+func (bp *Breakpoint) hit(cpu *cpu65c816.CPU) bool {
+	if bp.Condition != nil && !bp.Condition(cpu) {
+		return false
+	}
+	bp.Hits++
+	return bp.HitCount == 0 || bp.Hits == bp.HitCount
+}
+
+// StopKind says why Run returned
+type StopKind uint8
+
+const (
+	StopCycles     StopKind = iota // the cycle budget ran out
+	StopTarget                     // the CPU reached the target PC
+	StopBreakpoint                 // a breakpoint fired
+	StopCPUStopped                 // the CPU executed STP
+)
+
+func (k StopKind) String() string {
+	switch k {
+	case StopCycles:
+		return "cycles"
+	case StopTarget:
+		return "target"
+	case StopBreakpoint:
+		return "breakpoint"
+	case StopCPUStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// StopReason describes why Run returned. For breakpoints it carries the access that fired; read and write
+// breakpoints stop after the instruction that made the access completes.
+type StopReason struct {
+	Kind StopKind
+	// PC is the CPU's program counter when Run returned
+	PC uint32
+
+	Breakpoint *Breakpoint
+	Access     BreakKind
+	Address    uint32
+	Value      byte
+}
+
+func (r StopReason) String() string {
+	if r.Kind != StopBreakpoint {
+		return fmt.Sprintf("%s at $%06X", r.Kind, r.PC)
+	}
+	return fmt.Sprintf("breakpoint %s: %s $%06X = $%02X at $%06X", r.Breakpoint, r.Access, r.Address, r.Value, r.PC)
+}
+
+// Debugger holds the breakpoints of a System
+type Debugger struct {
+	s           *System
+	breakpoints []*Breakpoint
+	nextID      int
+
+	// the first breakpoint hit by an access during the current step:
+	pending *StopReason
+}
+
+// Debugger returns the system's debugger, installing it on first use
+func (s *System) Debugger() *Debugger {
+	if s.debugger == nil {
+		s.debugger = &Debugger{s: s, nextID: 1}
+		s.CPU.OnAccess = s.onAccess
+	}
+	return s.debugger
+}
+
+// mapper returns the cartridge mapper; CreateEmulator lays out the bus as LoROM
+func (s *System) mapper() mapping.Mapper {
+	if s.Mapper != nil {
+		return s.Mapper
+	}
+	m, _ := mapping.ForMapMode(0x20)
+	return m
+}
+
+// Break adds a breakpoint over [start, start+size) and every bus address that mirrors it
+func (d *Debugger) Break(kind BreakKind, start, size uint32) *Breakpoint {
+	if size == 0 {
+		size = 1
+	}
+	bp := &Breakpoint{ID: d.nextID, Kind: kind, Start: start, Size: size}
+	d.nextID++
+	bp.ranges = d.mirrors(start, size)
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp
+}
+
+// Remove deletes a breakpoint
+func (d *Debugger) Remove(bp *Breakpoint) {
+	for i, b := range d.breakpoints {
+		if b == bp {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clear deletes all breakpoints
+func (d *Debugger) Clear() {
+	d.breakpoints = nil
+	d.pending = nil
+}
+
+// Breakpoints lists the breakpoints in the order they were added
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	return d.breakpoints
+}
+
+// mirrors expands the bus range to all bus ranges that reach the same memory. Unmapped parts in the system I/O area
+// are repeated across the system banks; other unmapped parts are kept as given.
+func (d *Debugger) mirrors(start, size uint32) (ranges []busRange) {
+	m := d.s.mapper()
+	end := start + size
+	if end > 0x1000000 {
+		end = 0x1000000
+	}
+	unmapped := func(lo, hi uint32) {
+		for lo < hi {
+			bankEnd := lo&0xFF0000 + 0x10000
+			if bankEnd > hi {
+				bankEnd = hi
+			}
+			if !util.IsSystemArea(lo) {
+				ranges = append(ranges, busRange{lo, bankEnd})
+			} else {
+				for b := uint32(0); b < 0x100; b++ {
+					if b&0x40 != 0 {
+						continue
+					}
+					ranges = append(ranges, busRange{b<<16 | lo&0xFFFF, b<<16 | (bankEnd-1)&0xFFFF + 1})
+				}
+			}
+			lo = bankEnd
+		}
+	}
+
+	covered := start
+	for _, seg := range m.SplitBusRange(start, end-start) {
+		if seg.Bus > covered {
+			unmapped(covered, seg.Bus)
+		}
+		for _, mir := range m.MirrorRanges(seg.Pak, seg.Size) {
+			ranges = append(ranges, busRange{mir.Bus, mir.BusEnd()})
+		}
+		covered = seg.BusEnd()
+	}
+	if covered < end {
+		unmapped(covered, end)
+	}
+	return
+}
+
+// check counts a hit on every enabled breakpoint of the kind covering the address and returns the first that breaks
+func (d *Debugger) check(kind BreakKind, addr uint32) (first *Breakpoint) {
+	for _, bp := range d.breakpoints {
+		if bp.Disabled || bp.Kind&kind == 0 || !bp.contains(addr) {
+			continue
+		}
+		if bp.hit(&d.s.CPU) && first == nil {
+			first = bp
+		}
+	}
+	return
+}
+
+// executeBreak returns an execution breakpoint at the instruction the next step runs. A step that enters an interrupt
+// handler or idles in WAI runs no instruction.
+func (s *System) executeBreak() (reason StopReason, ok bool) {
+	d := s.debugger
+	if d == nil || s.CPU.InterruptPending() || s.CPU.Waiting {
+		return
+	}
+	pc := s.GetPC()
+	if bp := d.check(BreakExecute, pc); bp != nil {
+		return StopReason{Kind: StopBreakpoint, Breakpoint: bp, Access: BreakExecute, Address: pc}, true
+	}
+	return
+}
+
+// accessBreak returns and clears the first read or write breakpoint hit by the last step
+func (s *System) accessBreak() (reason StopReason, ok bool) {
+	d := s.debugger
+	if d == nil || d.pending == nil {
+		return
+	}
+	reason = *d.pending
+	d.pending = nil
+	return reason, true
+}
+
+func (s *System) clearAccessBreak() {
+	if s.debugger != nil {
+		s.debugger.pending = nil
+	}
+}
+
+func (d *Debugger) onAccess(ea uint32, value byte, kind cpu65c816.AccessKind) {
+	if len(d.breakpoints) == 0 {
+		return
+	}
+	var k BreakKind
+	switch kind {
+	case cpu65c816.AccessRead:
+		k = BreakRead
+	case cpu65c816.AccessWrite:
+		k = BreakWrite
+	default:
+		return
+	}
+	// later accesses of the step still count hits but the first break is reported:
+	if bp := d.check(k, ea); bp != nil && d.pending == nil {
+		d.pending = &StopReason{Kind: StopBreakpoint, Breakpoint: bp, Access: k, Address: ea, Value: value}
+	}
+}
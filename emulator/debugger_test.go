@@ -0,0 +1,190 @@
+package emulator
+
+import (
+	"reflect"
+	"testing"
+)
+
+// SEP #$20; LDA #5; STA $0100; loop: LDA $7E0100; DEC A; STA $0100; BNE loop; STP
+var debuggerTestCode = []byte{
+	0xE2, 0x20,
+	0xA9, 0x05,
+	0x8D, 0x00, 0x01,
+	0xAF, 0x00, 0x01, 0x7E,
+	0x3A,
+	0x8D, 0x00, 0x01,
+	0xD0, 0xF6,
+	0xDB,
+}
+
+func newDebuggerTest(t *testing.T) *System {
+	t.Helper()
+	return newCodeSystem(t, debuggerTestCode)
+}
+
+func TestDebugger_Break(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      BreakKind
+		addr      uint32
+		hitCount  uint64
+		condition Condition
+		want      StopReason
+	}{
+		{
+			name: "write through mirror",
+			kind: BreakWrite, addr: 0x7E_0100,
+			want: StopReason{Kind: StopBreakpoint, PC: 0x00_8007, Access: BreakWrite, Address: 0x00_0100, Value: 5},
+		},
+		{
+			name: "read through mirror",
+			kind: BreakRead, addr: 0x80_0100,
+			want: StopReason{Kind: StopBreakpoint, PC: 0x00_800B, Access: BreakRead, Address: 0x7E_0100, Value: 5},
+		},
+		{
+			name: "execute in FastROM mirror with hit count",
+			kind: BreakExecute, addr: 0x80_800C, hitCount: 3,
+			want: StopReason{Kind: StopBreakpoint, PC: 0x00_800C, Access: BreakExecute, Address: 0x00_800C},
+		},
+		{
+			name: "write with condition",
+			kind: BreakWrite, addr: 0x00_0100, condition: RegisterMasked(RegA, 0xFF, 1),
+			want: StopReason{Kind: StopBreakpoint, PC: 0x00_800F, Access: BreakWrite, Address: 0x00_0100, Value: 1},
+		},
+		{
+			name: "opcode fetches are not reads",
+			kind: BreakRead, addr: 0x00_8000, hitCount: 0,
+			want: StopReason{Kind: StopCPUStopped, PC: 0x00_8012},
+		},
+	}
+	for _, tt := range tests {
+		s := newDebuggerTest(t)
+		bp := s.Debugger().Break(tt.kind, tt.addr, 1)
+		bp.HitCount = tt.hitCount
+		bp.Condition = tt.condition
+
+		got := s.Run(0xFF_FFFF, 1000)
+		if got.Kind == StopBreakpoint && got.Breakpoint != bp {
+			t.Errorf("%s: stopped on %v, want %v", tt.name, got.Breakpoint, bp)
+		}
+		got.Breakpoint = nil
+		if got != tt.want {
+			t.Errorf("%s: Run() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDebugger_Resume(t *testing.T) {
+	s := newDebuggerTest(t)
+	bp := s.Debugger().Break(BreakExecute, 0x00_800B, 1)
+
+	var as []uint16
+	for {
+		r := s.Run(0xFF_FFFF, 1000)
+		if r.Kind != StopBreakpoint {
+			if r.Kind != StopCPUStopped {
+				t.Fatalf("Run() = %v", r)
+			}
+			break
+		}
+		as = append(as, RegA.Value(&s.CPU)&0xFF)
+	}
+	if len(as) != 5 || as[0] != 5 || as[4] != 1 {
+		t.Errorf("A at each hit = %v, want 5 down to 1", as)
+	}
+	if bp.Hits != 5 {
+		t.Errorf("hits = %d, want 5", bp.Hits)
+	}
+
+	// removed breakpoints no longer fire:
+	s.Debugger().Remove(bp)
+	s.CPU.Stopped = false
+	s.SetPC(0x00_8000)
+	if r := s.Run(0xFF_FFFF, 1000); r.Kind != StopCPUStopped {
+		t.Errorf("Run() = %v after Remove", r)
+	}
+}
+
+func TestDebugger_HitCount(t *testing.T) {
+	s := newDebuggerTest(t)
+	d := s.Debugger()
+	// the loop writes $0100 five times; both breakpoints count every write:
+	second := d.Break(BreakWrite, 0x00_0100, 1)
+	second.HitCount = 2
+	every := d.Break(BreakWrite, 0x7E_0100, 1)
+	every.Disabled = true
+	also := d.Break(BreakWrite, 0x00_0100, 1)
+
+	var stops []*Breakpoint
+	for {
+		r := s.Run(0xFF_FFFF, 1000)
+		if r.Kind != StopBreakpoint {
+			break
+		}
+		stops = append(stops, r.Breakpoint)
+	}
+
+	// the second hit is reported by the breakpoint registered first; HitCount does not break after its hit:
+	want := []*Breakpoint{also, second, also, also, also, also}
+	if !reflect.DeepEqual(stops, want) {
+		t.Errorf("stops = %v, want %v", stops, want)
+	}
+	if second.Hits != 6 || also.Hits != 6 || every.Hits != 0 {
+		t.Errorf("hits = %d, %d, %d, want 6, 6, 0", second.Hits, also.Hits, every.Hits)
+	}
+}
+
+func TestDebugger_BreakInterruptHandler(t *testing.T) {
+	s := newDebuggerTest(t)
+	// NMI handler at $00:8100: NOP; RTI
+	copy(s.ROM[0x0100:], []byte{0xEA, 0x40})
+	copy(s.ROM[0x7FEA:], []byte{0x00, 0x81})
+	s.CPU.OnPC = map[uint32]func(){0x00_8004: s.CPU.TriggerNMI}
+
+	// the NMI arrives before $00:8007 runs, so the handler's first instruction breaks and $00:8007 does not:
+	s.Debugger().Break(BreakExecute, 0x00_8007, 1)
+	bp := s.Debugger().Break(BreakExecute, 0x00_8100, 1)
+
+	want := StopReason{Kind: StopBreakpoint, PC: 0x00_8100, Access: BreakExecute, Address: 0x00_8100}
+	got := s.Run(0xFF_FFFF, 1000)
+	if got.Breakpoint != bp {
+		t.Errorf("stopped on %v, want %v", got.Breakpoint, bp)
+	}
+	got.Breakpoint = nil
+	if got != want {
+		t.Errorf("Run() = %v, want %v", got, want)
+	}
+}
+
+func TestDebugger_Mirrors(t *testing.T) {
+	s := newDebuggerTest(t)
+	d := s.Debugger()
+	tests := []struct {
+		addr    uint32
+		in, out []uint32
+	}{
+		{0x7E_0100, []uint32{0x00_0100, 0x3F_0100, 0x80_0100, 0xBF_0100}, []uint32{0x7F_0100, 0x70_0100, 0x7E_0101}},
+		{0x00_2100, []uint32{0x80_2100, 0x3F_2100}, []uint32{0x40_2100, 0x7E_2100, 0x00_2101}},
+		{0x00_8000, []uint32{0x80_8000}, []uint32{0x01_8000, 0x00_8001}},
+	}
+	for _, tt := range tests {
+		bp := d.Break(BreakRead, tt.addr, 1)
+		for _, a := range tt.in {
+			if !bp.contains(a) {
+				t.Errorf("breakpoint at $%06X does not cover $%06X", tt.addr, a)
+			}
+		}
+		for _, a := range tt.out {
+			if bp.contains(a) {
+				t.Errorf("breakpoint at $%06X covers $%06X", tt.addr, a)
+			}
+		}
+	}
+	if got := len(d.Breakpoints()); got != len(tests) {
+		t.Errorf("%d breakpoints, want %d", got, len(tests))
+	}
+	d.Clear()
+	if got := len(d.Breakpoints()); got != 0 {
+		t.Errorf("%d breakpoints after Clear", got)
+	}
+}
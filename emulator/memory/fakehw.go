@@ -1,5 +1,7 @@
 package memory
 
+import "fmt"
+
 type FakeHW struct {
 	state [0x6000]byte
 }
@@ -35,3 +37,19 @@ func (f *FakeHW) Clear() {
 func (f *FakeHW) Dump(address uint32) []byte {
 	return nil
 }
+
+// MarshalBinary returns a copy of the register state
+func (f *FakeHW) MarshalBinary() ([]byte, error) {
+	data := make([]byte, len(f.state))
+	copy(data, f.state[:])
+	return data, nil
+}
+
+// UnmarshalBinary restores register state returned by MarshalBinary
+func (f *FakeHW) UnmarshalBinary(data []byte) error {
+	if len(data) != len(f.state) {
+		return fmt.Errorf("fakehw: state is %d bytes, expected %d", len(data), len(f.state))
+	}
+	copy(f.state[:], data)
+	return nil
+}
@@ -0,0 +1,80 @@
+package emulator
+
+import (
+	"github.com/alttpo/snes/emulator/mmio"
+	"github.com/alttpo/snes/mapping/util"
+)
+
+// AttachMMIO attaches the register model over the $2000-$5FFF I/O area of banks $00-$3F and $80-$BF in place of
+// FakeHW. It backs the WRAM port with System.WRAM, preserving written pages for checkpoints as bus writes do, runs DMA
+// through System.Bus charging its time to the CPU, switches the CPU's ROM access speed on MEMSEL writes and paces the
+// registers by the CPU's master clock unless they already have a Clock.
+func (s *System) AttachMMIO(r *mmio.Registers) (err error) {
+	if r.WRAM == nil && r.WRAMPort == nil {
+		r.WRAM = s.WRAM[:]
+		r.WRAMPort = s.newRAM(areaWRAM, 0, uint32(len(s.WRAM)), 0)
+	}
+	if r.Bus == nil {
+		r.Bus = &s.Bus
+	}
+	if r.Charge == nil {
+		r.Charge = s.chargeClocks
+	}
+	if r.OnMEMSEL == nil {
+		r.OnMEMSEL = func(value byte) { s.CPU.FastROM = value&1 != 0 }
+	}
+	if r.Clock == nil {
+		r.Clock = func() uint64 { return s.CPU.AllClocks }
+	}
+
+	for b := uint32(0); b < 0x100; b++ {
+		bank := b << 16
+		if !util.IsSystemArea(bank | 0x2000) {
+			continue
+		}
+		if err = s.Bus.Attach(r, "mmio", bank|0x2000, bank|0x5FFF); err != nil {
+			return
+		}
+	}
+	s.HWIO = r
+	return
+}
+
+// chargeClocks adds master clocks spent outside the CPU, such as DMA, to the CPU's clock and cycle counters
+func (s *System) chargeClocks(clocks uint64) {
+	s.CPU.AllClocks += clocks
+	s.pendingClocks += clocks
+	s.CPU.AllCycles += s.pendingClocks / mmio.ClocksPerCycle
+	s.pendingClocks %= mmio.ClocksPerCycle
+}
+
+// VideoMemory is implemented by I/O handlers that model the PPU memories, such as mmio.Registers
+type VideoMemory interface {
+	VRAM() []byte
+	CGRAM() []byte
+	OAM() []byte
+}
+
+// VRAM returns the video RAM behind the $2116-$2119 ports; nil unless HWIO implements VideoMemory
+func (s *System) VRAM() []byte {
+	if v, ok := s.HWIO.(VideoMemory); ok {
+		return v.VRAM()
+	}
+	return nil
+}
+
+// CGRAM returns the palette RAM behind the $2121-$2122 ports; nil unless HWIO implements VideoMemory
+func (s *System) CGRAM() []byte {
+	if v, ok := s.HWIO.(VideoMemory); ok {
+		return v.CGRAM()
+	}
+	return nil
+}
+
+// OAM returns the object attribute memory behind the $2102-$2104 ports; nil unless HWIO implements VideoMemory
+func (s *System) OAM() []byte {
+	if v, ok := s.HWIO.(VideoMemory); ok {
+		return v.OAM()
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package mmio
+
+// Bus is the A-bus DMA transfers through; B-bus registers are reached at $00:2100-$00:21FF
+type Bus interface {
+	EaRead(a uint32) byte
+	EaWrite(a uint32, value byte)
+}
+
+// DMA timing in master clocks
+const (
+	DMAClocksPerByte    = 8
+	DMAClocksPerChannel = 8
+	DMAClocksOverhead   = 12
+	HDMAClocksOverhead  = 18
+)
+
+// Channel is the register set of a DMA channel at $43x0-$43xF
+type Channel struct {
+	DMAP   byte   // $43x0 parameters
+	BBAD   byte   // $43x1 B-bus address
+	A1T    uint16 // $43x2-$43x3 A-bus address / HDMA table address
+	A1B    byte   // $43x4 A-bus bank
+	DAS    uint16 // $43x5-$43x6 byte count / HDMA indirect address
+	DASB   byte   // $43x7 HDMA indirect bank
+	A2A    uint16 // $43x8-$43x9 HDMA table current address
+	NTRL   byte   // $43xA HDMA line counter
+	Unused byte   // $43xB and $43xF
+
+	DoTransfer byte // HDMA transfers this line
+	Completed  byte // HDMA table ended this frame
+}
+
+// transferPatterns lists the B-bus address offsets of one transfer unit for each mode
+var transferPatterns = [8][]byte{
+	{0},
+	{0, 1},
+	{0, 0},
+	{0, 0, 1, 1},
+	{0, 1, 2, 3},
+	{0, 1, 0, 1},
+	{0, 0},
+	{0, 0, 1, 1},
+}
+
+func (c *Channel) pattern() []byte { return transferPatterns[c.DMAP&7] }
+
+func (c *Channel) indirect() bool { return c.DMAP&0x40 != 0 }
+
+func (c *Channel) bToA() bool { return c.DMAP&0x80 != 0 }
+
+func (r *Registers) readChannel(offs uint16) byte {
+	c := &r.s.DMA[(offs>>4)&7]
+	switch offs & 0xF {
+	case 0x0:
+		return c.DMAP
+	case 0x1:
+		return c.BBAD
+	case 0x2:
+		return byte(c.A1T)
+	case 0x3:
+		return byte(c.A1T >> 8)
+	case 0x4:
+		return c.A1B
+	case 0x5:
+		return byte(c.DAS)
+	case 0x6:
+		return byte(c.DAS >> 8)
+	case 0x7:
+		return c.DASB
+	case 0x8:
+		return byte(c.A2A)
+	case 0x9:
+		return byte(c.A2A >> 8)
+	case 0xA:
+		return c.NTRL
+	case 0xB, 0xF:
+		return c.Unused
+	}
+	return r.s.MDR
+}
+
+func (r *Registers) writeChannel(offs uint16, value byte) {
+	c := &r.s.DMA[(offs>>4)&7]
+	switch offs & 0xF {
+	case 0x0:
+		c.DMAP = value
+	case 0x1:
+		c.BBAD = value
+	case 0x2:
+		c.A1T = c.A1T&0xFF00 | uint16(value)
+	case 0x3:
+		c.A1T = c.A1T&0x00FF | uint16(value)<<8
+	case 0x4:
+		c.A1B = value
+	case 0x5:
+		c.DAS = c.DAS&0xFF00 | uint16(value)
+	case 0x6:
+		c.DAS = c.DAS&0x00FF | uint16(value)<<8
+	case 0x7:
+		c.DASB = value
+	case 0x8:
+		c.A2A = c.A2A&0xFF00 | uint16(value)
+	case 0x9:
+		c.A2A = c.A2A&0x00FF | uint16(value)<<8
+	case 0xA:
+		c.NTRL = value
+	case 0xB, 0xF:
+		c.Unused = value
+	}
+}
+
+// validABus reports whether the A-bus address can take part in DMA; the B-bus and the DMA registers themselves
+// are not reachable from the A-bus side
+func validABus(addr uint32) bool {
+	if addr&0x400000 != 0 {
+		return true
+	}
+	offs := addr & 0xFFFF
+	return !(offs >= 0x2100 && offs < 0x2200) && !(offs >= 0x4000 && offs < 0x4400)
+}
+
+// transfer moves one byte between the A-bus and the B-bus in the channel's direction
+func (r *Registers) transfer(c *Channel, aAddr uint32, bOffs byte) {
+	bAddr := 0x2100 | uint32(c.BBAD+bOffs)
+	if c.bToA() {
+		v := r.Bus.EaRead(bAddr)
+		if validABus(aAddr) {
+			r.Bus.EaWrite(aAddr, v)
+		}
+	} else {
+		v := r.s.MDR
+		if validABus(aAddr) {
+			v = r.Bus.EaRead(aAddr)
+		}
+		r.Bus.EaWrite(bAddr, v)
+	}
+}
+
+func (r *Registers) charge(clocks uint64) {
+	if r.Charge != nil {
+		r.Charge(clocks)
+	}
+}
+
+// runDMA performs general purpose DMA for each channel enabled in MDMAEN, lowest channel first
+func (r *Registers) runDMA(enable byte) {
+	if r.Bus == nil || enable == 0 {
+		return
+	}
+	clocks := uint64(DMAClocksOverhead)
+	for i := range r.s.DMA {
+		if enable&(1<<uint(i)) == 0 {
+			continue
+		}
+		c := &r.s.DMA[i]
+		clocks += DMAClocksPerChannel
+		pattern := c.pattern()
+		for n := 0; ; n++ {
+			r.transfer(c, uint32(c.A1B)<<16|uint32(c.A1T), pattern[n%len(pattern)])
+			clocks += DMAClocksPerByte
+
+			// A-bus address steps within its bank; bit 3 fixes it, bit 4 decrements:
+			if c.DMAP&0x08 == 0 {
+				if c.DMAP&0x10 != 0 {
+					c.A1T--
+				} else {
+					c.A1T++
+				}
+			}
+			// a count of 0 transfers 65536 bytes:
+			c.DAS--
+			if c.DAS == 0 {
+				break
+			}
+		}
+	}
+	r.s.MDMAEN = 0
+	r.charge(clocks)
+}
+
+func (r *Registers) hdmaRead(c *Channel) byte {
+	v := r.Bus.EaRead(uint32(c.A1B)<<16 | uint32(c.A2A))
+	c.A2A++
+	return v
+}
+
+// hdmaReload loads the next line counter and, in indirect mode, the data address from the table
+func (r *Registers) hdmaReload(c *Channel) (clocks uint64) {
+	c.NTRL = r.hdmaRead(c)
+	clocks += DMAClocksPerByte
+	if c.indirect() {
+		lo := r.hdmaRead(c)
+		hi := r.hdmaRead(c)
+		c.DAS = uint16(hi)<<8 | uint16(lo)
+		clocks += 2 * DMAClocksPerByte
+	}
+	if c.NTRL == 0 {
+		c.Completed = 1
+	}
+	c.DoTransfer = 1
+	return
+}
+
+// HDMAInit starts the HDMA tables for the frame; call it at the start of each frame
+func (r *Registers) HDMAInit() {
+	for i := range r.s.DMA {
+		c := &r.s.DMA[i]
+		c.Completed = 0
+		c.DoTransfer = 0
+	}
+	if r.Bus == nil || r.s.HDMAEN == 0 {
+		return
+	}
+	clocks := uint64(HDMAClocksOverhead)
+	for i := range r.s.DMA {
+		if r.s.HDMAEN&(1<<uint(i)) == 0 {
+			continue
+		}
+		c := &r.s.DMA[i]
+		c.A2A = c.A1T
+		clocks += DMAClocksPerChannel + r.hdmaReload(c)
+	}
+	r.charge(clocks)
+}
+
+// HDMALine runs one scanline of HDMA; call it at the start of horizontal blank of each visible line
+func (r *Registers) HDMALine() {
+	if r.Bus == nil || r.s.HDMAEN == 0 {
+		return
+	}
+	clocks := uint64(HDMAClocksOverhead)
+	for i := range r.s.DMA {
+		c := &r.s.DMA[i]
+		if r.s.HDMAEN&(1<<uint(i)) == 0 || c.Completed != 0 {
+			continue
+		}
+		clocks += DMAClocksPerChannel
+
+		if c.DoTransfer != 0 {
+			for _, bOffs := range c.pattern() {
+				var aAddr uint32
+				if c.indirect() {
+					aAddr = uint32(c.DASB)<<16 | uint32(c.DAS)
+					c.DAS++
+				} else {
+					aAddr = uint32(c.A1B)<<16 | uint32(c.A2A)
+					c.A2A++
+				}
+				r.transfer(c, aAddr, bOffs)
+				clocks += DMAClocksPerByte
+			}
+		}
+
+		c.NTRL--
+		c.DoTransfer = c.NTRL >> 7
+		if c.NTRL&0x7F == 0 {
+			clocks += r.hdmaReload(c)
+		}
+	}
+	r.charge(clocks)
+}
+
+// Channel returns a copy of a DMA channel's registers
+func (r *Registers) Channel(i int) Channel {
+	return r.s.DMA[i&7]
+}
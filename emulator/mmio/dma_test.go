@@ -0,0 +1,177 @@
+package mmio
+
+import (
+	"testing"
+)
+
+// testBus is a flat 16MB bus that records B-bus writes
+type testBus struct {
+	mem    [0x1000000]byte
+	bWrite []uint32
+	bValue []byte
+}
+
+func (b *testBus) EaRead(a uint32) byte { return b.mem[a&0xFFFFFF] }
+
+func (b *testBus) EaWrite(a uint32, value byte) {
+	if a&0xFFFF00 == 0x2100 {
+		b.bWrite = append(b.bWrite, a)
+		b.bValue = append(b.bValue, value)
+	}
+	b.mem[a&0xFFFFFF] = value
+}
+
+func newDMATest() (*Registers, *testBus, *uint64) {
+	b := &testBus{}
+	r := New(nil)
+	r.Bus = b
+	clocks := new(uint64)
+	r.Charge = func(n uint64) { *clocks += n }
+	return r, b, clocks
+}
+
+func setupChannel(r *Registers, ch int, dmap, bbad byte, addr uint32, count uint16) {
+	base := 0x4300 | uint32(ch)<<4
+	r.Write(base+0, dmap)
+	r.Write(base+1, bbad)
+	r.Write(base+2, byte(addr))
+	r.Write(base+3, byte(addr>>8))
+	r.Write(base+4, byte(addr>>16))
+	r.Write(base+5, byte(count))
+	r.Write(base+6, byte(count>>8))
+}
+
+func TestRegisters_DMAModes(t *testing.T) {
+	tests := []struct {
+		mode byte
+		want []uint32
+	}{
+		{0, []uint32{0x2118, 0x2118, 0x2118, 0x2118}},
+		{1, []uint32{0x2118, 0x2119, 0x2118, 0x2119}},
+		{2, []uint32{0x2118, 0x2118, 0x2118, 0x2118}},
+		{3, []uint32{0x2118, 0x2118, 0x2119, 0x2119}},
+		{4, []uint32{0x2118, 0x2119, 0x211A, 0x211B}},
+		{5, []uint32{0x2118, 0x2119, 0x2118, 0x2119}},
+		{6, []uint32{0x2118, 0x2118, 0x2118, 0x2118}},
+		{7, []uint32{0x2118, 0x2118, 0x2119, 0x2119}},
+	}
+	for _, tt := range tests {
+		r, b, clocks := newDMATest()
+		copy(b.mem[0x7E1000:], []byte{1, 2, 3, 4})
+		setupChannel(r, 0, tt.mode, 0x18, 0x7E1000, 4)
+		r.Write(0x420B, 0x01)
+
+		if len(b.bWrite) != len(tt.want) {
+			t.Fatalf("mode %d: %d writes, want %d", tt.mode, len(b.bWrite), len(tt.want))
+		}
+		for i := range tt.want {
+			if b.bWrite[i] != tt.want[i] || b.bValue[i] != byte(i+1) {
+				t.Errorf("mode %d: write %d = $%04X <- %d, want $%04X <- %d", tt.mode, i, b.bWrite[i], b.bValue[i], tt.want[i], i+1)
+			}
+		}
+		if want := uint64(DMAClocksOverhead + DMAClocksPerChannel + 4*DMAClocksPerByte); *clocks != want {
+			t.Errorf("mode %d: charged %d clocks, want %d", tt.mode, *clocks, want)
+		}
+		ch := r.Channel(0)
+		if ch.DAS != 0 || ch.A1T != 0x1004 {
+			t.Errorf("mode %d: DAS = $%04X, A1T = $%04X after transfer", tt.mode, ch.DAS, ch.A1T)
+		}
+	}
+}
+
+func TestRegisters_DMAStep(t *testing.T) {
+	tests := []struct {
+		name string
+		dmap byte
+		want []byte
+		a1t  uint16
+	}{
+		{"increment", 0x00, []byte{1, 2, 3}, 0x1003},
+		{"fixed", 0x08, []byte{1, 1, 1}, 0x1000},
+		{"decrement", 0x10, []byte{1, 0xF, 0xE}, 0x0FFD},
+	}
+	for _, tt := range tests {
+		r, b, _ := newDMATest()
+		copy(b.mem[0x7E1000:], []byte{1, 2, 3})
+		b.mem[0x7E0FFF] = 0xF
+		b.mem[0x7E0FFE] = 0xE
+		setupChannel(r, 0, tt.dmap, 0x80, 0x7E1000, 3)
+		r.Write(0x420B, 0x01)
+
+		if string(b.bValue) != string(tt.want) {
+			t.Errorf("%s: wrote %v, want %v", tt.name, b.bValue, tt.want)
+		}
+		if got := r.Channel(0).A1T; got != tt.a1t {
+			t.Errorf("%s: A1T = $%04X, want $%04X", tt.name, got, tt.a1t)
+		}
+	}
+}
+
+func TestRegisters_DMABToA(t *testing.T) {
+	r, b, _ := newDMATest()
+	b.mem[0x2180] = 0x5A
+	setupChannel(r, 3, 0x80, 0x80, 0x7F0000, 2)
+	r.Write(0x420B, 0x08)
+	if b.mem[0x7F0000] != 0x5A || b.mem[0x7F0001] != 0x5A {
+		t.Errorf("B to A wrote $%02X $%02X, want $5A $5A", b.mem[0x7F0000], b.mem[0x7F0001])
+	}
+}
+
+func TestRegisters_ChannelRegisters(t *testing.T) {
+	r := New(nil)
+	for offs := uint32(0); offs < 0x0B; offs++ {
+		r.Write(0x4350+offs, byte(0xA0+offs))
+	}
+	r.Write(0x435F, 0x77)
+	for offs := uint32(0); offs < 0x0B; offs++ {
+		if got := r.Read(0x4350 + offs); got != byte(0xA0+offs) {
+			t.Errorf("$%04X = $%02X, want $%02X", 0x4350+offs, got, 0xA0+offs)
+		}
+	}
+	// $43xB and $43xF share a byte:
+	if got := r.Read(0x435B); got != 0x77 {
+		t.Errorf("$435B = $%02X, want $77", got)
+	}
+	// MDMAEN without a bus only records the value:
+	r.Write(0x420B, 0x20)
+	if got := r.Channel(5).DAS; got != 0xA6A5 {
+		t.Errorf("DAS = $%04X, want $A6A5", got)
+	}
+}
+
+func TestRegisters_HDMA(t *testing.T) {
+	r, b, _ := newDMATest()
+	// direct table: 2 lines of $11, 1 line repeating $22,$33 then end
+	copy(b.mem[0x7E2000:], []byte{0x02, 0x11, 0x82, 0x22, 0x33, 0x00})
+	setupChannel(r, 1, 0x00, 0x05, 0x7E2000, 0)
+	r.Write(0x420C, 0x02)
+
+	r.HDMAInit()
+	for i := 0; i < 5; i++ {
+		r.HDMALine()
+	}
+	if want := []byte{0x11, 0x22, 0x33}; string(b.bValue) != string(want) {
+		t.Errorf("direct HDMA wrote %v, want %v", b.bValue, want)
+	}
+	if r.Channel(1).Completed == 0 {
+		t.Errorf("direct HDMA not completed")
+	}
+
+	// indirect table: 1 line from $7E:3000, then end
+	r, b, _ = newDMATest()
+	copy(b.mem[0x7E2000:], []byte{0x01, 0x00, 0x30, 0x00})
+	copy(b.mem[0x7E3000:], []byte{0xAA, 0xBB})
+	setupChannel(r, 0, 0x41, 0x26, 0x7E2000, 0)
+	r.Write(0x4307, 0x7E)
+	r.Write(0x420C, 0x01)
+
+	r.HDMAInit()
+	r.HDMALine()
+	r.HDMALine()
+	if want := []byte{0xAA, 0xBB}; string(b.bValue) != string(want) {
+		t.Errorf("indirect HDMA wrote %v, want %v", b.bValue, want)
+	}
+	if want := []uint32{0x2126, 0x2127}; len(b.bWrite) != 2 || b.bWrite[0] != want[0] || b.bWrite[1] != want[1] {
+		t.Errorf("indirect HDMA wrote to %x, want %x", b.bWrite, want)
+	}
+}
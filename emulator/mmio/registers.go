@@ -0,0 +1,495 @@
+// Package mmio models the SNES CPU and B-bus I/O registers at $2100-$21FF and $4000-$43FF.
+package mmio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/alttpo/snes/emulator/memory"
+	"github.com/alttpo/snes/timing"
+)
+
+// ClocksPerCycle is the master clocks of a CPU internal cycle, which paces the multiply and divide unit
+const ClocksPerCycle = timing.FastClocks
+
+// state is the serializable register state
+type state struct {
+	Other [0x6000]byte // last value written to registers without a model
+
+	MDR byte // last value transferred through the registers
+
+	// WRAM port $2180-$2183:
+	WMADD uint32
+
+	// PPU multiply $211B/$211C -> $2134-$2136:
+	M7Prev byte
+	M7A    uint16
+	M7B    uint8
+
+	// PPU counter latches $2137, $213C, $213D, $213F:
+	OPHCT, OPVCT     uint16
+	OPHCTHi, OPVCTHi byte
+	Latched          byte
+
+	// APU ports $2140-$2143:
+	APUIn  [4]byte
+	APUOut [4]byte
+
+	// CPU registers $4200-$421F:
+	NMITIMEN byte
+	WRIO     byte
+	HTIME    uint16
+	VTIME    uint16
+	MDMAEN   byte
+	HDMAEN   byte
+	MEMSEL   byte
+	NMIFlag  byte
+	IRQFlag  byte
+	Joypad   [4]uint16
+
+	// ALU:
+	WRMPYA uint8
+	WRMPYB uint8
+	WRDIVA uint16
+	WRDIVB uint8
+	RDDIV  uint16
+	RDMPY  uint16
+	MPYCtr uint8
+	DivCtr uint8
+	Shift  uint32
+	ALUClk uint64
+
+	// DMA channels $4300-$437F:
+	DMA [8]Channel
+
+	video
+
+	// beam flag tracking when derived from Clock:
+	NMIClk  uint64
+	HVBJOY  byte
+	Toggles uint64
+}
+
+// Registers models the CPU I/O registers and the B-bus registers, including the ports to the PPU's VRAM, CGRAM and
+// OAM. Rendering is not modeled, so the ports accept access during active display as well as in blanking.
+//
+// The multiply and divide unit follows bsnes: results are produced one bit per CPU cycle over 8 (multiply) or 16
+// (divide) cycles of 6 master clocks after the write to WRMPYB or WRDIVB, so reading early returns partial results.
+type Registers struct {
+	// WRAM backs the $2180-$2183 WRAM port
+	WRAM []byte
+
+	// WRAMPort, when set, is accessed at WRAM offsets $00000-$1FFFF by the WRAM port in place of WRAM, so that
+	// writes through $2180 are seen by whatever tracks writes to WRAM
+	WRAMPort memory.Memory
+
+	// Clock returns the current master clock count. It paces the math unit and, unless Beam is set, derives the
+	// beam position for HVBJOY and RDNMI. When nil, math results are immediate and HVBJOY alternates its blank flags
+	// on every read so that polling loops terminate.
+	Clock func() uint64
+
+	// Standard sets the frame length and blanking lines of the beam position derived from Clock
+	Standard timing.Standard
+
+	// Beam returns the current dot and scanline, overriding the position derived from Clock. A scheduler that sets
+	// Beam also sets NMI and IRQ flags with SetNMIFlag and SetIRQFlag.
+	Beam func() (h, v uint16)
+
+	// APUEcho makes reads of $2140-$2143 return the last value written to that port, completing the IPL handshake
+	// without an SPC700. The ports read $AA, $BB, $00, $00 until written.
+	APUEcho bool
+
+	// OnAPUWrite is called for writes to $2140-$2143
+	OnAPUWrite func(port int, value byte)
+
+	// OnMEMSEL is called for writes to $420D so that the CPU can switch ROM access speed
+	OnMEMSEL func(value byte)
+
+	// Bus is used by DMA and HDMA transfers; without it writes to MDMAEN only record the value
+	Bus Bus
+
+	// Charge is called with the master clocks the CPU is halted for by DMA and HDMA
+	Charge func(clocks uint64)
+
+	s state
+}
+
+// New returns Registers in their power-on state with APU echo enabled
+func New(wram []byte) *Registers {
+	r := &Registers{WRAM: wram, APUEcho: true}
+	r.Reset()
+	return r
+}
+
+// Reset returns the registers to their power-on state
+func (r *Registers) Reset() {
+	r.s = state{
+		APUOut: [4]byte{0xAA, 0xBB, 0x00, 0x00},
+		WRIO:   0xFF,
+		HTIME:  0x1FF,
+		VTIME:  0x1FF,
+		WRMPYA: 0xFF,
+		WRDIVA: 0xFFFF,
+	}
+	r.s.NMIClk = r.now()
+	r.s.ALUClk = r.now()
+}
+
+func (r *Registers) now() uint64 {
+	if r.Clock == nil {
+		return 0
+	}
+	return r.Clock()
+}
+
+// SetJoypad sets the value auto-joypad read reports in JOY1-JOY4 ($4218-$421F)
+func (r *Registers) SetJoypad(port int, buttons uint16) {
+	r.s.Joypad[port&3] = buttons
+}
+
+// SetNMIFlag sets the RDNMI ($4210) flag, as at the start of vertical blank
+func (r *Registers) SetNMIFlag() { r.s.NMIFlag = 0x80 }
+
+// ClearNMIFlag clears the RDNMI flag, as at the end of vertical blank
+func (r *Registers) ClearNMIFlag() { r.s.NMIFlag = 0 }
+
+// SetIRQFlag sets the TIMEUP ($4211) flag, as when the H/V timer fires
+func (r *Registers) SetIRQFlag() { r.s.IRQFlag = 0x80 }
+
+// IRQFlag reports whether the TIMEUP flag is set, which holds the CPU IRQ line low
+func (r *Registers) IRQFlag() bool { return r.s.IRQFlag != 0 }
+
+// NMITIMEN returns the interrupt enable register $4200
+func (r *Registers) NMITIMEN() byte { return r.s.NMITIMEN }
+
+// HVTime returns the H and V timer compare values from $4207-$420A
+func (r *Registers) HVTime() (h, v uint16) { return r.s.HTIME, r.s.VTIME }
+
+// MEMSEL returns $420D; bit 0 enables FastROM timing for banks $80-$FF
+func (r *Registers) MEMSEL() byte { return r.s.MEMSEL }
+
+// Overscan reports whether SETINI ($2133) selects the 239-line mode
+func (r *Registers) Overscan() bool { return r.s.Other[0x2133-0x2000]&0x04 != 0 }
+
+// beam returns the current dot and scanline
+func (r *Registers) beam() (h, v uint16) {
+	if r.Beam != nil {
+		return r.Beam()
+	}
+	t := r.now() % r.Standard.ClocksPerFrame()
+	return uint16(t % timing.ClocksPerLine / timing.ClocksPerDot), uint16(t / timing.ClocksPerLine)
+}
+
+func (r *Registers) vblankLine() uint16 {
+	return r.Standard.VBlankLine(r.Overscan())
+}
+
+// vblankStarts counts the vertical blank starts at or before master clock t
+func (r *Registers) vblankStarts(t uint64) uint64 {
+	start := uint64(r.vblankLine()) * timing.ClocksPerLine
+	if t < start {
+		return 0
+	}
+	return (t-start)/r.Standard.ClocksPerFrame() + 1
+}
+
+// syncNMIFlag sets the NMI flag if a vertical blank started since the last RDNMI read when the beam derives from Clock
+func (r *Registers) syncNMIFlag() {
+	if r.Beam != nil || r.Clock == nil {
+		return
+	}
+	now := r.now()
+	if r.vblankStarts(now) > r.vblankStarts(r.s.NMIClk) {
+		r.s.NMIFlag = 0x80
+	}
+	r.s.NMIClk = now
+}
+
+func (r *Registers) hvbjoy() (value byte) {
+	if r.Beam == nil && r.Clock == nil {
+		// no notion of time; alternate so that both wait-for-blank and wait-for-not-blank loops terminate:
+		r.s.Toggles++
+		if r.s.Toggles&1 != 0 {
+			return 0xC0
+		}
+		return 0x00
+	}
+
+	h, v := r.beam()
+	vbl := r.vblankLine()
+	if v >= vbl {
+		value |= 0x80
+		// auto-joypad read runs for about three lines at the start of vertical blank:
+		if r.s.NMITIMEN&0x01 != 0 && v < vbl+3 {
+			value |= 0x01
+		}
+	}
+	if h >= timing.HBlankStartDot || h < timing.HBlankEndDot {
+		value |= 0x40
+	}
+	return
+}
+
+// syncALU advances the math unit to the current clock
+func (r *Registers) syncALU() {
+	now := r.now()
+	steps := uint64(r.s.MPYCtr) + uint64(r.s.DivCtr)
+	if steps == 0 {
+		r.s.ALUClk = now
+		return
+	}
+	if r.Clock != nil {
+		if elapsed := (now - r.s.ALUClk) / ClocksPerCycle; elapsed < steps {
+			steps = elapsed
+		}
+		r.s.ALUClk += steps * ClocksPerCycle
+	}
+	for ; steps > 0; steps-- {
+		r.aluStep()
+	}
+}
+
+func (r *Registers) aluStep() {
+	if r.s.MPYCtr != 0 {
+		r.s.MPYCtr--
+		if r.s.RDDIV&1 != 0 {
+			r.s.RDMPY += uint16(r.s.Shift)
+		}
+		r.s.RDDIV >>= 1
+		r.s.Shift <<= 1
+	}
+	if r.s.DivCtr != 0 {
+		r.s.DivCtr--
+		r.s.RDDIV <<= 1
+		r.s.Shift >>= 1
+		if uint32(r.s.RDMPY) >= r.s.Shift {
+			r.s.RDMPY -= uint16(r.s.Shift)
+			r.s.RDDIV |= 1
+		}
+	}
+}
+
+func (r *Registers) m7Product() uint32 {
+	return uint32(int32(int16(r.s.M7A)) * int32(int8(r.s.M7B)))
+}
+
+// Read reads a register; address is a 24-bit bus address
+func (r *Registers) Read(address uint32) (value byte) {
+	offs := uint16(address)
+	value = r.s.MDR
+	switch {
+	case offs >= 0x2134 && offs <= 0x2136:
+		value = byte(r.m7Product() >> (8 * (offs - 0x2134)))
+	case offs == 0x2137:
+		// SLHV: latch the counters
+		r.latchCounters()
+	case offs == 0x213C:
+		value = byte(r.s.OPHCT >> (8 * uint16(r.s.OPHCTHi)))
+		r.s.OPHCTHi ^= 1
+	case offs == 0x213D:
+		value = byte(r.s.OPVCT >> (8 * uint16(r.s.OPVCTHi)))
+		r.s.OPVCTHi ^= 1
+	case offs == 0x213F:
+		// STAT78: latch flag and PPU2 version
+		value = r.s.Latched<<6 | 0x03
+		r.s.Latched = 0
+		r.s.OPHCTHi, r.s.OPVCTHi = 0, 0
+	case offs >= 0x2138 && offs <= 0x213B:
+		value, _ = r.readVideo(offs)
+	case offs >= 0x2140 && offs < 0x2180:
+		value = r.s.APUOut[offs&3]
+	case offs == 0x2180:
+		if r.WRAMPort != nil {
+			value = r.WRAMPort.Read(r.s.WMADD)
+		} else if len(r.WRAM) != 0 {
+			value = r.WRAM[int(r.s.WMADD)%len(r.WRAM)]
+		}
+		r.s.WMADD = (r.s.WMADD + 1) & 0x1FFFF
+	case offs >= 0x2100 && offs < 0x2200:
+		// other B-bus registers are write-only
+	case offs == 0x4016 || offs == 0x4017:
+		// serial joypad reads: no controller shifting, report no buttons
+		value = r.s.MDR & 0xFC
+	case offs == 0x4210:
+		r.syncNMIFlag()
+		value = r.s.NMIFlag | r.s.MDR&0x70 | 0x02
+		r.s.NMIFlag = 0
+	case offs == 0x4211:
+		value = r.s.IRQFlag | r.s.MDR&0x7F
+		r.s.IRQFlag = 0
+	case offs == 0x4212:
+		value = r.hvbjoy() | r.s.MDR&0x3E
+	case offs == 0x4213:
+		value = r.s.WRIO
+	case offs >= 0x4214 && offs <= 0x4217:
+		r.syncALU()
+		switch offs {
+		case 0x4214:
+			value = byte(r.s.RDDIV)
+		case 0x4215:
+			value = byte(r.s.RDDIV >> 8)
+		case 0x4216:
+			value = byte(r.s.RDMPY)
+		case 0x4217:
+			value = byte(r.s.RDMPY >> 8)
+		}
+	case offs >= 0x4218 && offs <= 0x421F:
+		j := r.s.Joypad[(offs-0x4218)>>1]
+		value = byte(j >> (8 * (offs & 1)))
+	case offs >= 0x4200 && offs < 0x4220:
+		// other CPU registers are write-only
+	case offs >= 0x4300 && offs < 0x4380:
+		value = r.readChannel(offs)
+	case offs >= 0x2000 && offs < 0x6000:
+		value = r.s.Other[offs-0x2000]
+	}
+	r.s.MDR = value
+	return
+}
+
+func (r *Registers) latchCounters() {
+	h, v := r.beam()
+	r.s.OPHCT, r.s.OPVCT = h, v
+	r.s.Latched = 1
+}
+
+// Write writes a register; address is a 24-bit bus address
+func (r *Registers) Write(address uint32, value byte) {
+	offs := uint16(address)
+	r.s.MDR = value
+	if offs >= 0x2000 && offs < 0x6000 {
+		r.s.Other[offs-0x2000] = value
+	}
+	switch {
+	case offs >= 0x2102 && offs <= 0x2104, offs >= 0x2115 && offs <= 0x2119, offs == 0x2121, offs == 0x2122:
+		r.writeVideo(offs, value)
+	case offs == 0x211B:
+		r.s.M7A = uint16(value)<<8 | uint16(r.s.M7Prev)
+		r.s.M7Prev = value
+	case offs == 0x211C:
+		r.s.M7B = value
+		r.s.M7Prev = value
+	case offs >= 0x2140 && offs < 0x2180:
+		port := int(offs & 3)
+		r.s.APUIn[port] = value
+		if r.APUEcho {
+			r.s.APUOut[port] = value
+		}
+		if r.OnAPUWrite != nil {
+			r.OnAPUWrite(port, value)
+		}
+	case offs == 0x2180:
+		if r.WRAMPort != nil {
+			r.WRAMPort.Write(r.s.WMADD, value)
+		} else if len(r.WRAM) != 0 {
+			r.WRAM[int(r.s.WMADD)%len(r.WRAM)] = value
+		}
+		r.s.WMADD = (r.s.WMADD + 1) & 0x1FFFF
+	case offs == 0x2181:
+		r.s.WMADD = r.s.WMADD&0x1FF00 | uint32(value)
+	case offs == 0x2182:
+		r.s.WMADD = r.s.WMADD&0x100FF | uint32(value)<<8
+	case offs == 0x2183:
+		r.s.WMADD = r.s.WMADD&0x0FFFF | uint32(value&1)<<16
+	case offs == 0x4200:
+		r.s.NMITIMEN = value
+		if value&0x30 == 0 {
+			r.s.IRQFlag = 0
+		}
+	case offs == 0x4201:
+		if r.s.WRIO&0x80 != 0 && value&0x80 == 0 {
+			r.latchCounters()
+		}
+		r.s.WRIO = value
+	case offs == 0x4202:
+		r.syncALU()
+		r.s.WRMPYA = value
+	case offs == 0x4203:
+		r.syncALU()
+		r.s.RDMPY = 0
+		if r.s.MPYCtr != 0 || r.s.DivCtr != 0 {
+			return
+		}
+		r.s.WRMPYB = value
+		r.s.RDDIV = uint16(value)<<8 | uint16(r.s.WRMPYA)
+		r.s.MPYCtr = 8
+		r.s.Shift = uint32(value)
+		r.startALU()
+	case offs == 0x4204:
+		r.syncALU()
+		r.s.WRDIVA = r.s.WRDIVA&0xFF00 | uint16(value)
+	case offs == 0x4205:
+		r.syncALU()
+		r.s.WRDIVA = r.s.WRDIVA&0x00FF | uint16(value)<<8
+	case offs == 0x4206:
+		r.syncALU()
+		r.s.RDMPY = r.s.WRDIVA
+		if r.s.MPYCtr != 0 || r.s.DivCtr != 0 {
+			return
+		}
+		r.s.WRDIVB = value
+		r.s.DivCtr = 16
+		r.s.Shift = uint32(value) << 16
+		r.startALU()
+	case offs == 0x4207:
+		r.s.HTIME = r.s.HTIME&0x100 | uint16(value)
+	case offs == 0x4208:
+		r.s.HTIME = r.s.HTIME&0x0FF | uint16(value&1)<<8
+	case offs == 0x4209:
+		r.s.VTIME = r.s.VTIME&0x100 | uint16(value)
+	case offs == 0x420A:
+		r.s.VTIME = r.s.VTIME&0x0FF | uint16(value&1)<<8
+	case offs == 0x420B:
+		r.s.MDMAEN = value
+		r.runDMA(value)
+	case offs == 0x420C:
+		r.s.HDMAEN = value
+	case offs == 0x420D:
+		r.s.MEMSEL = value
+		if r.OnMEMSEL != nil {
+			r.OnMEMSEL(value)
+		}
+	case offs >= 0x4300 && offs < 0x4380:
+		r.writeChannel(offs, value)
+	}
+}
+
+func (r *Registers) startALU() {
+	r.s.ALUClk = r.now()
+	if r.Clock == nil {
+		r.syncALU()
+	}
+}
+
+func (r *Registers) Shutdown() {
+}
+
+func (r *Registers) Size() uint32 {
+	return 0x4000
+}
+
+func (r *Registers) Clear() {
+	r.Reset()
+}
+
+func (r *Registers) Dump(address uint32) []byte {
+	return nil
+}
+
+// MarshalBinary serializes the register state
+func (r *Registers) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, &r.s); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary restores register state returned by MarshalBinary
+func (r *Registers) UnmarshalBinary(data []byte) error {
+	if len(data) != binary.Size(&r.s) {
+		return fmt.Errorf("mmio: state is %d bytes, expected %d", len(data), binary.Size(&r.s))
+	}
+	return binary.Read(bytes.NewReader(data), binary.LittleEndian, &r.s)
+}
@@ -0,0 +1,273 @@
+package mmio
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes/timing"
+)
+
+func TestRegisters_Multiply(t *testing.T) {
+	tests := []struct {
+		a, b uint8
+	}{
+		{0x00, 0x00},
+		{0x12, 0x34},
+		{0xFF, 0xFF},
+		{0x80, 0x02},
+	}
+	for _, tt := range tests {
+		r := New(nil)
+		r.Write(0x4202, tt.a)
+		r.Write(0x4203, tt.b)
+		got := uint16(r.Read(0x4216)) | uint16(r.Read(0x4217))<<8
+		if want := uint16(tt.a) * uint16(tt.b); got != want {
+			t.Errorf("$%02X * $%02X = $%04X, want $%04X", tt.a, tt.b, got, want)
+		}
+		// RDDIV holds the multiplier after the operation:
+		if got := r.Read(0x4214); got != tt.b {
+			t.Errorf("RDDIVL = $%02X, want $%02X", got, tt.b)
+		}
+	}
+}
+
+func TestRegisters_Divide(t *testing.T) {
+	tests := []struct {
+		a        uint16
+		b        uint8
+		quotient uint16
+		rem      uint16
+	}{
+		{0x1234, 0x10, 0x0123, 0x0004},
+		{0xFFFF, 0xFF, 0x0101, 0x0000},
+		{0x0005, 0x07, 0x0000, 0x0005},
+		// division by zero:
+		{0x1234, 0x00, 0xFFFF, 0x1234},
+	}
+	for _, tt := range tests {
+		r := New(nil)
+		r.Write(0x4204, byte(tt.a))
+		r.Write(0x4205, byte(tt.a>>8))
+		r.Write(0x4206, tt.b)
+		q := uint16(r.Read(0x4214)) | uint16(r.Read(0x4215))<<8
+		rem := uint16(r.Read(0x4216)) | uint16(r.Read(0x4217))<<8
+		if q != tt.quotient || rem != tt.rem {
+			t.Errorf("$%04X / $%02X = $%04X r $%04X, want $%04X r $%04X", tt.a, tt.b, q, rem, tt.quotient, tt.rem)
+		}
+	}
+}
+
+func TestRegisters_MultiplyLatency(t *testing.T) {
+	var clock uint64
+	r := New(nil)
+	r.Clock = func() uint64 { return clock }
+
+	r.Write(0x4202, 0xFF)
+	r.Write(0x4203, 0xFF)
+	if got := r.Read(0x4216); got != 0x00 {
+		t.Errorf("immediately after start RDMPYL = $%02X, want $00", got)
+	}
+
+	// four cycles in, four bits of the multiplicand have been processed:
+	clock += 4 * ClocksPerCycle
+	got := uint16(r.Read(0x4216)) | uint16(r.Read(0x4217))<<8
+	if want := uint16(0x0F) * 0xFF; got != want {
+		t.Errorf("after 4 cycles RDMPY = $%04X, want $%04X", got, want)
+	}
+
+	clock += 4 * ClocksPerCycle
+	got = uint16(r.Read(0x4216)) | uint16(r.Read(0x4217))<<8
+	if want := uint16(0xFF) * 0xFF; got != want {
+		t.Errorf("after 8 cycles RDMPY = $%04X, want $%04X", got, want)
+	}
+}
+
+func TestRegisters_DivideLatency(t *testing.T) {
+	var clock uint64
+	r := New(nil)
+	r.Clock = func() uint64 { return clock }
+
+	r.Write(0x4204, 0x00)
+	r.Write(0x4205, 0x80)
+	r.Write(0x4206, 0x02)
+	clock += 15 * ClocksPerCycle
+	if got := uint16(r.Read(0x4214)) | uint16(r.Read(0x4215))<<8; got == 0x4000 {
+		t.Error("quotient complete after 15 cycles")
+	}
+	clock += ClocksPerCycle
+	if got := uint16(r.Read(0x4214)) | uint16(r.Read(0x4215))<<8; got != 0x4000 {
+		t.Errorf("after 16 cycles quotient = $%04X, want $4000", got)
+	}
+}
+
+func TestRegisters_PPUMultiply(t *testing.T) {
+	r := New(nil)
+	// M7A = -2, M7B = 3:
+	r.Write(0x211B, 0xFE)
+	r.Write(0x211B, 0xFF)
+	r.Write(0x211C, 0x03)
+	got := uint32(r.Read(0x2134)) | uint32(r.Read(0x2135))<<8 | uint32(r.Read(0x2136))<<16
+	if got != 0xFFFFFA {
+		t.Errorf("-2 * 3 = $%06X, want $FFFFFA", got)
+	}
+}
+
+func TestRegisters_WRAMPort(t *testing.T) {
+	wram := make([]byte, 0x20000)
+	r := New(wram)
+	r.Write(0x2181, 0xFF)
+	r.Write(0x2182, 0xFF)
+	r.Write(0x2183, 0x00)
+	r.Write(0x2180, 0x11)
+	r.Write(0x2180, 0x22)
+	if wram[0x0FFFF] != 0x11 || wram[0x10000] != 0x22 {
+		t.Errorf("WMDATA writes = $%02X $%02X", wram[0x0FFFF], wram[0x10000])
+	}
+
+	r.Write(0x2183, 0x01)
+	r.Write(0x2182, 0xFF)
+	r.Write(0x2181, 0xFF)
+	wram[0x1FFFF] = 0x33
+	wram[0x00000] = 0x44
+	if got := r.Read(0x2180); got != 0x33 {
+		t.Errorf("WMDATA read = $%02X, want $33", got)
+	}
+	if got := r.Read(0x802180); got != 0x44 {
+		t.Errorf("WMDATA read after wrap = $%02X, want $44", got)
+	}
+}
+
+func TestRegisters_APU(t *testing.T) {
+	r := New(nil)
+	if r.Read(0x2140) != 0xAA || r.Read(0x2141) != 0xBB {
+		t.Error("APU ports do not report IPL ready")
+	}
+	var port int
+	var value byte
+	r.OnAPUWrite = func(p int, v byte) { port, value = p, v }
+	r.Write(0x2140, 0xCC)
+	if r.Read(0x2140) != 0xCC {
+		t.Error("APU port did not echo")
+	}
+	if port != 0 || value != 0xCC {
+		t.Errorf("OnAPUWrite(%d, $%02X)", port, value)
+	}
+	// mirrored every four bytes:
+	if r.Read(0x2144) != 0xCC {
+		t.Error("APU port mirror did not echo")
+	}
+}
+
+func TestRegisters_HVBJOY(t *testing.T) {
+	var h, v uint16
+	r := New(nil)
+	r.Beam = func() (uint16, uint16) { return h, v }
+
+	tests := []struct {
+		h, v uint16
+		auto bool
+		want byte
+	}{
+		{h: 100, v: 100, want: 0x00},
+		{h: 300, v: 100, want: 0x40},
+		{h: 100, v: 225, want: 0x80},
+		{h: 100, v: 225, auto: true, want: 0x81},
+		{h: 0, v: 261, auto: true, want: 0xC0},
+	}
+	for _, tt := range tests {
+		h, v = tt.h, tt.v
+		nmitimen := byte(0)
+		if tt.auto {
+			nmitimen = 0x01
+		}
+		r.Write(0x4200, nmitimen)
+		if got := r.Read(0x4212) & 0xC1; got != tt.want {
+			t.Errorf("HVBJOY at %d,%d = $%02X, want $%02X", tt.h, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRegisters_HVBJOY_Standard(t *testing.T) {
+	var clock uint64
+	r := New(nil)
+	r.Clock = func() uint64 { return clock }
+
+	// line 300 is in vertical blank of a PAL frame; an NTSC frame has wrapped to line 38 by then:
+	clock = 300*timing.ClocksPerLine + 100*timing.ClocksPerDot
+	tests := []struct {
+		standard timing.Standard
+		want     byte
+	}{
+		{timing.NTSC, 0x00},
+		{timing.PAL, 0x80},
+	}
+	for _, tt := range tests {
+		r.Standard = tt.standard
+		if got := r.Read(0x4212) & 0xC0; got != tt.want {
+			t.Errorf("%s: HVBJOY = $%02X, want $%02X", tt.standard, got, tt.want)
+		}
+	}
+}
+
+func TestRegisters_HVBJOY_NoClock(t *testing.T) {
+	r := New(nil)
+	a, b := r.Read(0x4212)&0x80, r.Read(0x4212)&0x80
+	if a == b {
+		t.Error("HVBJOY does not alternate without a clock")
+	}
+}
+
+func TestRegisters_RDNMI(t *testing.T) {
+	var clock uint64
+	r := New(nil)
+	r.Clock = func() uint64 { return clock }
+
+	if r.Read(0x4210)&0x80 != 0 {
+		t.Error("NMI flag set before vertical blank")
+	}
+	clock = 225*timing.ClocksPerLine + 10
+	if got := r.Read(0x4210); got&0x80 == 0 || got&0x0F != 0x02 {
+		t.Errorf("RDNMI = $%02X, want flag and version 2", got)
+	}
+	if r.Read(0x4210)&0x80 != 0 {
+		t.Error("NMI flag not cleared by read")
+	}
+	clock += timing.NTSC.ClocksPerFrame()
+	if r.Read(0x4210)&0x80 == 0 {
+		t.Error("NMI flag not set in the next frame")
+	}
+
+	r.SetIRQFlag()
+	if r.Read(0x4211)&0x80 == 0 || r.Read(0x4211)&0x80 != 0 {
+		t.Error("TIMEUP flag not set then cleared")
+	}
+}
+
+func TestRegisters_Joypad(t *testing.T) {
+	r := New(nil)
+	r.SetJoypad(1, 0x8040)
+	if r.Read(0x421A) != 0x40 || r.Read(0x421B) != 0x80 {
+		t.Error("JOY2 does not report buttons")
+	}
+}
+
+func TestRegisters_MarshalBinary(t *testing.T) {
+	r := New(nil)
+	r.Write(0x4202, 0x12)
+	r.Write(0x2100, 0x8F)
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := New(nil)
+	if err = q.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	q.Write(0x4203, 0x02)
+	if q.Read(0x4216) != 0x24 {
+		t.Error("WRMPYA not restored")
+	}
+	if err = q.UnmarshalBinary(data[1:]); err == nil {
+		t.Error("UnmarshalBinary() accepted short state")
+	}
+}
@@ -0,0 +1,175 @@
+package mmio
+
+// Video memory sizes
+const (
+	VRAMSize  = 0x10000
+	CGRAMSize = 0x200
+	OAMSize   = 0x220
+)
+
+// video is the PPU memories and the state of their ports
+type video struct {
+	VRAM  [VRAMSize]byte
+	CGRAM [CGRAMSize]byte
+	OAM   [OAMSize]byte
+
+	// VRAM port $2115-$2119, $2139-$213A:
+	VMAIN     byte
+	VMADD     uint16 // word address
+	VRAMLatch uint16 // read prefetch
+
+	// CGRAM port $2121-$2122, $213B:
+	CGADD    byte // word address
+	CGHigh   byte // next access is the high byte
+	CGLatch  byte // low byte of a pending write
+	CGReadHi byte
+
+	// OAM port $2102-$2104, $2138:
+	OAMReload uint16 // word address written to $2102-$2103
+	OAMADD    uint16 // byte address
+	OAMLatch  byte   // low byte of a pending write
+	OAMPrio   byte   // $2103 bit 7
+}
+
+// VRAM returns the 64KB of video RAM, two bytes per word address
+func (r *Registers) VRAM() []byte { return r.s.VRAM[:] }
+
+// CGRAM returns the 512 bytes of palette RAM, two bytes per color
+func (r *Registers) CGRAM() []byte { return r.s.CGRAM[:] }
+
+// OAM returns the 544 bytes of object attribute memory; the last 32 bytes are the high table
+func (r *Registers) OAM() []byte { return r.s.OAM[:] }
+
+// vramStep returns the word address increment selected by VMAIN
+func (r *Registers) vramStep() uint16 {
+	switch r.s.VMAIN & 3 {
+	case 0:
+		return 1
+	case 1:
+		return 32
+	default:
+		return 128
+	}
+}
+
+// vramAddress applies the VMAIN address remapping to the word address and returns a byte offset into VRAM
+func (r *Registers) vramAddress() uint32 {
+	a := r.s.VMADD
+	switch (r.s.VMAIN >> 2) & 3 {
+	case 1:
+		a = a&0xFF00 | (a&0x001F)<<3 | (a>>5)&7
+	case 2:
+		a = a&0xFE00 | (a&0x003F)<<3 | (a>>6)&7
+	case 3:
+		a = a&0xFC00 | (a&0x007F)<<3 | (a>>7)&7
+	}
+	return uint32(a&0x7FFF) << 1
+}
+
+// incHigh reports whether the VRAM address increments after the high byte access rather than the low
+func (r *Registers) incHigh() bool { return r.s.VMAIN&0x80 != 0 }
+
+func (r *Registers) vramPrefetch() {
+	a := r.vramAddress()
+	r.s.VRAMLatch = uint16(r.s.VRAM[a]) | uint16(r.s.VRAM[a+1])<<8
+}
+
+func (r *Registers) oamReload() {
+	r.s.OAMADD = r.s.OAMReload << 1 & 0x3FF
+}
+
+// readVideo reads a video memory port; ok is false for other registers
+func (r *Registers) readVideo(offs uint16) (value byte, ok bool) {
+	switch offs {
+	case 0x2138:
+		a := r.s.OAMADD
+		if a&0x200 != 0 {
+			value = r.s.OAM[0x200|a&0x1F]
+		} else {
+			value = r.s.OAM[a]
+		}
+		r.s.OAMADD = (a + 1) & 0x3FF
+	case 0x2139:
+		value = byte(r.s.VRAMLatch)
+		if !r.incHigh() {
+			r.vramPrefetch()
+			r.s.VMADD += r.vramStep()
+		}
+	case 0x213A:
+		value = byte(r.s.VRAMLatch >> 8)
+		if r.incHigh() {
+			r.vramPrefetch()
+			r.s.VMADD += r.vramStep()
+		}
+	case 0x213B:
+		a := uint16(r.s.CGADD) << 1
+		if r.s.CGReadHi == 0 {
+			value = r.s.CGRAM[a]
+		} else {
+			// bit 7 is PPU2 open bus:
+			value = r.s.CGRAM[a+1]&0x7F | r.s.MDR&0x80
+			r.s.CGADD++
+		}
+		r.s.CGReadHi ^= 1
+	default:
+		return 0, false
+	}
+	return value, true
+}
+
+// writeVideo writes a video memory port; writes are accepted regardless of forced blank
+func (r *Registers) writeVideo(offs uint16, value byte) {
+	switch offs {
+	case 0x2102:
+		r.s.OAMReload = r.s.OAMReload&0x100 | uint16(value)
+		r.oamReload()
+	case 0x2103:
+		r.s.OAMReload = r.s.OAMReload&0x0FF | uint16(value&1)<<8
+		r.s.OAMPrio = value & 0x80
+		r.oamReload()
+	case 0x2104:
+		a := r.s.OAMADD
+		if a&0x200 != 0 {
+			r.s.OAM[0x200|a&0x1F] = value
+		} else if a&1 == 0 {
+			r.s.OAMLatch = value
+		} else {
+			// the low table is written a word at a time:
+			r.s.OAM[a-1] = r.s.OAMLatch
+			r.s.OAM[a] = value
+		}
+		r.s.OAMADD = (a + 1) & 0x3FF
+	case 0x2115:
+		r.s.VMAIN = value
+	case 0x2116:
+		r.s.VMADD = r.s.VMADD&0xFF00 | uint16(value)
+		r.vramPrefetch()
+	case 0x2117:
+		r.s.VMADD = r.s.VMADD&0x00FF | uint16(value)<<8
+		r.vramPrefetch()
+	case 0x2118:
+		r.s.VRAM[r.vramAddress()] = value
+		if !r.incHigh() {
+			r.s.VMADD += r.vramStep()
+		}
+	case 0x2119:
+		r.s.VRAM[r.vramAddress()+1] = value
+		if r.incHigh() {
+			r.s.VMADD += r.vramStep()
+		}
+	case 0x2121:
+		r.s.CGADD = value
+		r.s.CGHigh = 0
+		r.s.CGReadHi = 0
+	case 0x2122:
+		if r.s.CGHigh == 0 {
+			r.s.CGLatch = value
+		} else {
+			a := uint16(r.s.CGADD) << 1
+			r.s.CGRAM[a] = r.s.CGLatch
+			r.s.CGRAM[a+1] = value & 0x7F
+			r.s.CGADD++
+		}
+		r.s.CGHigh ^= 1
+	}
+}
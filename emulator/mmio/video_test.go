@@ -0,0 +1,161 @@
+package mmio
+
+import (
+	"testing"
+)
+
+func TestRegisters_VRAMIncrement(t *testing.T) {
+	tests := []struct {
+		name  string
+		vmain byte
+		write []uint32
+		want  []uint16 // word addresses written
+	}{
+		{"low, step 1", 0x00, []uint32{0x2118, 0x2118}, []uint16{0x1000, 0x1001}},
+		{"high, step 1", 0x80, []uint32{0x2118, 0x2119, 0x2118, 0x2119}, []uint16{0x1000, 0x1000, 0x1001, 0x1001}},
+		{"high, step 32", 0x81, []uint32{0x2119, 0x2119}, []uint16{0x1000, 0x1020}},
+		{"high, step 128", 0x82, []uint32{0x2119, 0x2119}, []uint16{0x1000, 0x1080}},
+		{"high, step 128 (3)", 0x83, []uint32{0x2119, 0x2119}, []uint16{0x1000, 0x1080}},
+	}
+	for _, tt := range tests {
+		r := New(nil)
+		r.Write(0x2115, tt.vmain)
+		r.Write(0x2116, 0x00)
+		r.Write(0x2117, 0x10)
+		for i, a := range tt.write {
+			r.Write(a, byte(i+1))
+		}
+		for i, w := range tt.want {
+			offs := uint32(w)<<1 | tt.write[i]&1
+			if got := r.VRAM()[offs]; got != byte(i+1) {
+				t.Errorf("%s: VRAM[$%04X] = $%02X, want $%02X", tt.name, offs, got, i+1)
+			}
+		}
+	}
+}
+
+func TestRegisters_VRAMRemap(t *testing.T) {
+	tests := []struct {
+		remap byte
+		addr  uint16
+		want  uint16
+	}{
+		{0, 0x1234, 0x1234},
+		{1, 0x0021, 0x0009},
+		{2, 0x0041, 0x0009},
+		{3, 0x0081, 0x0009},
+		{1, 0x7FFF, 0x7FFF},
+	}
+	for _, tt := range tests {
+		r := New(nil)
+		r.Write(0x2115, 0x80|tt.remap<<2)
+		r.Write(0x2116, byte(tt.addr))
+		r.Write(0x2117, byte(tt.addr>>8))
+		r.Write(0x2118, 0x5A)
+		if got := r.VRAM()[uint32(tt.want)<<1]; got != 0x5A {
+			t.Errorf("remap %d of $%04X: VRAM word $%04X = $%02X, want $5A", tt.remap, tt.addr, tt.want, got)
+		}
+	}
+}
+
+func TestRegisters_VRAMPrefetch(t *testing.T) {
+	r := New(nil)
+	copy(r.VRAM()[0x200:], []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66})
+
+	// increment after high byte; setting the address fills the latch:
+	r.Write(0x2115, 0x80)
+	r.Write(0x2116, 0x00)
+	r.Write(0x2117, 0x01)
+	var got []byte
+	for i := 0; i < 3; i++ {
+		got = append(got, r.Read(0x2139), r.Read(0x213A))
+	}
+	// the latch refills from the address before it increments, so the first word is read twice:
+	if want := []byte{0x11, 0x22, 0x11, 0x22, 0x33, 0x44}; string(got) != string(want) {
+		t.Errorf("reads = % X, want % X", got, want)
+	}
+
+	// writes do not refill the latch:
+	r.Write(0x2116, 0x00)
+	r.Write(0x2118, 0x99)
+	if v := r.Read(0x2139); v != 0x11 {
+		t.Errorf("read after write = $%02X, want stale $11", v)
+	}
+}
+
+func TestRegisters_CGRAM(t *testing.T) {
+	r := New(nil)
+	r.Write(0x2121, 0x10)
+	for _, v := range []byte{0x1F, 0xFC, 0xE0, 0x03} {
+		r.Write(0x2122, v)
+	}
+	if got := r.CGRAM()[0x20:0x24]; string(got) != string([]byte{0x1F, 0x7C, 0xE0, 0x03}) {
+		t.Errorf("CGRAM = % X", got)
+	}
+
+	// a low byte alone is not written:
+	r.Write(0x2121, 0x20)
+	r.Write(0x2122, 0xAA)
+	if got := r.CGRAM()[0x40]; got != 0 {
+		t.Errorf("CGRAM[$40] = $%02X before high byte", got)
+	}
+
+	r.Write(0x2121, 0x10)
+	r.Write(0x2000, 0x80) // open bus bit 7
+	got := []byte{r.Read(0x213B), r.Read(0x213B), r.Read(0x213B)}
+	if want := []byte{0x1F, 0x7C, 0xE0}; got[0] != want[0] || got[1]&0x7F != want[1] || got[2] != want[2] {
+		t.Errorf("CGRAM reads = % X, want % X", got, want)
+	}
+}
+
+func TestRegisters_OAM(t *testing.T) {
+	r := New(nil)
+	r.Write(0x2102, 0x02)
+	r.Write(0x2103, 0x00)
+	r.Write(0x2104, 0xAA)
+	if got := r.OAM()[4]; got != 0 {
+		t.Errorf("OAM[4] = $%02X before the odd byte", got)
+	}
+	r.Write(0x2104, 0xBB)
+	if got := r.OAM()[4:6]; got[0] != 0xAA || got[1] != 0xBB {
+		t.Errorf("OAM[4:6] = % X", got)
+	}
+
+	// the high table is written a byte at a time and mirrors over $220-$3FF:
+	r.Write(0x2102, 0x00)
+	r.Write(0x2103, 0x01)
+	r.Write(0x2104, 0x55)
+	if got := r.OAM()[0x200]; got != 0x55 {
+		t.Errorf("OAM[$200] = $%02X, want $55", got)
+	}
+	r.Write(0x2102, 0x10)
+	r.Write(0x2103, 0x81)
+	r.Write(0x2104, 0x66)
+	if got := r.OAM()[0x200]; got != 0x66 {
+		t.Errorf("OAM[$200] via mirror = $%02X, want $66", got)
+	}
+
+	r.Write(0x2102, 0x02)
+	r.Write(0x2103, 0x00)
+	if got := []byte{r.Read(0x2138), r.Read(0x2138)}; got[0] != 0xAA || got[1] != 0xBB {
+		t.Errorf("OAM reads = % X", got)
+	}
+}
+
+func TestRegisters_VideoSnapshot(t *testing.T) {
+	r := New(nil)
+	r.Write(0x2115, 0x80)
+	r.Write(0x2118, 0x12)
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := New(nil)
+	if err = q.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	q.Write(0x2119, 0x34)
+	if got := q.VRAM()[0:2]; got[0] != 0x12 || got[1] != 0x34 {
+		t.Errorf("VRAM after restore = % X", got)
+	}
+}
@@ -0,0 +1,178 @@
+package emulator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alttpo/snes/emulator/mmio"
+)
+
+func TestSystem_AttachMMIO(t *testing.T) {
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	r := mmio.New(nil)
+	if err := s.AttachMMIO(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// SEP #$20; LDA #$0C; STA $4202; LDA #$0B; STA $4203; NOP x4; LDA $4216; STA $7E0010;
+	// STZ $2181; STZ $2182; STZ $2183; LDA #$99; STA $2180
+	copy(s.ROM[0:], []byte{
+		0xE2, 0x20,
+		0xA9, 0x0C, 0x8D, 0x02, 0x42,
+		0xA9, 0x0B, 0x8D, 0x03, 0x42,
+		0xEA, 0xEA, 0xEA, 0xEA,
+		0xAD, 0x16, 0x42, 0x8F, 0x10, 0x00, 0x7E,
+		0x9C, 0x81, 0x21, 0x9C, 0x82, 0x21, 0x9C, 0x83, 0x21,
+		0xA9, 0x99, 0x8D, 0x80, 0x21,
+		0xEA,
+	})
+	s.SetPC(0x00_8000)
+	if !s.RunUntil(0x00_8025, 200) {
+		t.Fatalf("did not reach target, PC = $%06X", s.GetPC())
+	}
+	if s.WRAM[0] != 0x99 {
+		t.Errorf("WMDATA write = $%02X, want $99", s.WRAM[0])
+	}
+	if s.WRAM[1] != 0x00 {
+		t.Errorf("WMDATA did not increment")
+	}
+
+	if s.WRAM[0x10] != 0x84 {
+		t.Errorf("RDMPYL = $%02X, want $84", s.WRAM[0x10])
+	}
+
+	// register state is part of snapshots:
+	var b bytes.Buffer
+	if err := s.Snapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+	s.Bus.EaWrite(0x00_4202, 0x01)
+	if err := s.Restore(&b); err != nil {
+		t.Fatal(err)
+	}
+	s.Bus.EaWrite(0x00_4203, 0x01)
+	s.CPU.AllClocks += 8 * mmio.ClocksPerCycle
+	if got := s.Bus.EaRead(0x00_4216); got != 0x0C {
+		t.Errorf("WRMPYA after restore = $%02X, want $0C", got)
+	}
+}
+
+func TestSystem_AttachMMIO_DMA(t *testing.T) {
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AttachMMIO(mmio.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	copy(s.ROM[0x1000:], []byte{0x10, 0x20, 0x30, 0x40, 0x50})
+
+	// WMADD = $7E:0100; channel 2 copies 5 bytes from $00:9000 to WMDATA:
+	for _, w := range []struct {
+		addr  uint32
+		value byte
+	}{
+		{0x2181, 0x00}, {0x2182, 0x01}, {0x2183, 0x00},
+		{0x4320, 0x00}, {0x4321, 0x80},
+		{0x4322, 0x00}, {0x4323, 0x90}, {0x4324, 0x00},
+		{0x4325, 0x05}, {0x4326, 0x00},
+	} {
+		s.Bus.EaWrite(w.addr, w.value)
+	}
+	before := s.CPU.AllCycles
+	s.Bus.EaWrite(0x420B, 0x04)
+
+	if got := s.WRAM[0x100:0x105]; !bytes.Equal(got, []byte{0x10, 0x20, 0x30, 0x40, 0x50}) {
+		t.Errorf("WRAM = % X after DMA", got)
+	}
+	clocks := uint64(mmio.DMAClocksOverhead + mmio.DMAClocksPerChannel + 5*mmio.DMAClocksPerByte)
+	if got := s.CPU.AllCycles - before; got != clocks/mmio.ClocksPerCycle {
+		t.Errorf("DMA charged %d cycles, want %d", got, clocks/mmio.ClocksPerCycle)
+	}
+}
+
+func TestSystem_VideoMemory(t *testing.T) {
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	if s.VRAM() != nil {
+		t.Errorf("VRAM without mmio registers")
+	}
+	if err := s.AttachMMIO(mmio.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	copy(s.ROM[0x1000:], []byte{0x01, 0x02, 0x03, 0x04})
+
+	// SEP #$20; LDA #$80; STA $2115; STZ $2116; LDA #$40; STA $2117;
+	// LDA #$01; STA $4300; LDA #$18; STA $4301; LDX #$9000; STX $4302; STZ $4304; LDX #$0004; STX $4305;
+	// LDA #$01; STA $420B; STZ $2121; LDA #$FF; STA $2122; STA $2122
+	copy(s.ROM[0:], []byte{
+		0xE2, 0x20,
+		0xA9, 0x80, 0x8D, 0x15, 0x21, 0x9C, 0x16, 0x21, 0xA9, 0x40, 0x8D, 0x17, 0x21,
+		0xA9, 0x01, 0x8D, 0x00, 0x43, 0xA9, 0x18, 0x8D, 0x01, 0x43,
+		0xA2, 0x00, 0x90, 0x8E, 0x02, 0x43, 0x9C, 0x04, 0x43, 0xA2, 0x04, 0x00, 0x8E, 0x05, 0x43,
+		0xA9, 0x01, 0x8D, 0x0B, 0x42,
+		0x9C, 0x21, 0x21, 0xA9, 0xFF, 0x8D, 0x22, 0x21, 0x8D, 0x22, 0x21,
+		0xEA,
+	})
+	s.SetPC(0x00_8000)
+	if !s.RunUntil(0x00_8038, 400) {
+		t.Fatalf("did not reach target, PC = $%06X", s.GetPC())
+	}
+	if got := s.VRAM()[0x8000:0x8004]; !bytes.Equal(got, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("VRAM = % X after DMA", got)
+	}
+	if got := s.CGRAM()[0:2]; !bytes.Equal(got, []byte{0xFF, 0x7F}) {
+		t.Errorf("CGRAM = % X", got)
+	}
+}
+
+func TestSystem_AttachMMIO_WRAMPortCheckpoint(t *testing.T) {
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AttachMMIO(mmio.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	s.WRAM[0x1_2345] = 0x11
+	s.WRAM[0x0_0100] = 0x22
+	copy(s.ROM[0x1000:], []byte{0xAA})
+
+	cp, err := s.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WMDATA write to $7F:2345, then DMA of one byte from $00:9000 to WMDATA at $7E:0100:
+	for _, w := range []struct {
+		addr  uint32
+		value byte
+	}{
+		{0x2181, 0x45}, {0x2182, 0x23}, {0x2183, 0x01}, {0x2180, 0x99},
+		{0x2181, 0x00}, {0x2182, 0x01}, {0x2183, 0x00},
+		{0x4300, 0x00}, {0x4301, 0x80},
+		{0x4302, 0x00}, {0x4303, 0x90}, {0x4304, 0x00},
+		{0x4305, 0x01}, {0x4306, 0x00},
+		{0x420B, 0x01},
+	} {
+		s.Bus.EaWrite(w.addr, w.value)
+	}
+	if s.WRAM[0x1_2345] != 0x99 || s.WRAM[0x0_0100] != 0xAA {
+		t.Fatalf("WRAM port writes = $%02X $%02X, want $99 $AA", s.WRAM[0x1_2345], s.WRAM[0x0_0100])
+	}
+
+	if err = s.Rewind(cp); err != nil {
+		t.Fatal(err)
+	}
+	if s.WRAM[0x1_2345] != 0x11 {
+		t.Errorf("WMDATA write after rewind = $%02X, want $11", s.WRAM[0x1_2345])
+	}
+	if s.WRAM[0x0_0100] != 0x22 {
+		t.Errorf("DMA to WMDATA after rewind = $%02X, want $22", s.WRAM[0x0_0100])
+	}
+}
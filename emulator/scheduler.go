@@ -0,0 +1,175 @@
+package emulator
+
+import (
+	"errors"
+
+	"github.com/alttpo/snes/emulator/mmio"
+	"github.com/alttpo/snes/timing"
+)
+
+var ErrNoMMIO = errors.New("emulator: scheduler requires mmio registers; call AttachMMIO first")
+
+// Scheduler runs the CPU against the master clock, tracking the beam position and raising the vertical blank NMI,
+// H/V timer IRQs and HDMA at the points in each scanline where the hardware does.
+//
+// The clock is the CPU's AllClocks counter, which the CPU advances by the speed of each bus access, so snapshots and
+// checkpoints restore the beam position along with the CPU.
+type Scheduler struct {
+	Standard timing.Standard
+
+	s *System
+	r *mmio.Registers
+}
+
+// NewScheduler drives the mmio registers attached to the system with the scheduler's clock and beam position
+func NewScheduler(s *System, standard timing.Standard) (*Scheduler, error) {
+	r, ok := s.HWIO.(*mmio.Registers)
+	if !ok {
+		return nil, ErrNoMMIO
+	}
+	sc := &Scheduler{Standard: standard, s: s, r: r}
+	r.Standard = standard
+	r.Clock = sc.Clock
+	r.Beam = sc.Beam
+	return sc, nil
+}
+
+// Clock returns the master clocks elapsed since power-on
+func (sc *Scheduler) Clock() uint64 { return sc.s.CPU.AllClocks }
+
+// Frame returns the number of frames completed
+func (sc *Scheduler) Frame() uint64 { return sc.Clock() / sc.Standard.ClocksPerFrame() }
+
+// Beam returns the current dot and scanline
+func (sc *Scheduler) Beam() (h, v uint16) {
+	t := sc.Clock() % sc.Standard.ClocksPerFrame()
+	return uint16(t % timing.ClocksPerLine / timing.ClocksPerDot), uint16(t / timing.ClocksPerLine)
+}
+
+// Step executes one CPU instruction and processes the beam events that occurred during it. While the CPU waits in
+// WAI the clock skips ahead to the next event instead. It returns the master clocks elapsed.
+func (sc *Scheduler) Step() uint64 {
+	cpu := &sc.s.CPU
+	start := cpu.AllClocks
+
+	// the IRQ line is held while TIMEUP is set:
+	if sc.r.IRQFlag() {
+		cpu.TriggerIRQ()
+	}
+
+	if cpu.Waiting {
+		cpu.AllClocks = sc.nextEvent(start) + 1
+	} else {
+		sc.s.step()
+	}
+
+	// events may charge more time for HDMA; keep going until caught up:
+	for from := start; from < cpu.AllClocks; {
+		to := cpu.AllClocks
+		sc.events(from, to)
+		from = to
+	}
+
+	// raise the IRQ now so that the next step is known to enter the handler:
+	if sc.r.IRQFlag() {
+		cpu.TriggerIRQ()
+	}
+	return cpu.AllClocks - start
+}
+
+// RunFrames runs until n more frames have completed, a breakpoint of the system's debugger fires or the CPU executes
+// STP. It returns StopCycles when the frames completed. As with Run, execution breakpoints at the starting PC are
+// skipped so that RunFrames can resume from one.
+func (sc *Scheduler) RunFrames(n int) (reason StopReason) {
+	s := sc.s
+	s.clearAccessBreak()
+	end := (sc.Frame() + uint64(n)) * sc.Standard.ClocksPerFrame()
+	reason.Kind = StopCycles
+	for first := true; sc.Clock() < end; first = false {
+		if s.CPU.Stopped {
+			reason.Kind = StopCPUStopped
+			break
+		}
+		if !first {
+			if r, ok := s.executeBreak(); ok {
+				reason = r
+				break
+			}
+		}
+		sc.Step()
+		if r, ok := s.accessBreak(); ok {
+			reason = r
+			break
+		}
+	}
+	reason.PC = s.GetPC()
+	return
+}
+
+func (sc *Scheduler) vblankLine() uint16 {
+	return sc.Standard.VBlankLine(sc.r.Overscan())
+}
+
+// irqDot returns the dot within line v at which the H/V timer fires, if it does
+func (sc *Scheduler) irqDot(v uint16) (dot uint16, ok bool) {
+	htime, vtime := sc.r.HVTime()
+	switch sc.r.NMITIMEN() >> 4 & 3 {
+	case 1:
+		return htime, htime < timing.DotsPerLine
+	case 2:
+		return 0, v == vtime
+	case 3:
+		return htime, v == vtime && htime < timing.DotsPerLine
+	}
+	return 0, false
+}
+
+// events fires the beam events with clocks in [from, to)
+func (sc *Scheduler) events(from, to uint64) {
+	lines := uint64(sc.Standard.LinesPerFrame())
+	for line := from / timing.ClocksPerLine; line*timing.ClocksPerLine < to; line++ {
+		base := line * timing.ClocksPerLine
+		in := func(dot uint16) bool {
+			t := base + uint64(dot)*timing.ClocksPerDot
+			return t >= from && t < to
+		}
+		v := uint16(line % lines)
+		vbl := sc.vblankLine()
+
+		if in(0) {
+			switch v {
+			case 0:
+				sc.r.ClearNMIFlag()
+				sc.r.HDMAInit()
+			case vbl:
+				sc.r.SetNMIFlag()
+				if sc.r.NMITIMEN()&0x80 != 0 {
+					sc.s.CPU.TriggerNMI()
+				}
+			}
+		}
+		if dot, ok := sc.irqDot(v); ok && in(dot) {
+			sc.r.SetIRQFlag()
+		}
+		if v < vbl && in(timing.HBlankStartDot) {
+			sc.r.HDMALine()
+		}
+	}
+}
+
+// nextEvent returns the clock of the first beam event after t
+func (sc *Scheduler) nextEvent(t uint64) uint64 {
+	base := t / timing.ClocksPerLine * timing.ClocksPerLine
+	next := base + timing.ClocksPerLine
+	v := uint16(base % sc.Standard.ClocksPerFrame() / timing.ClocksPerLine)
+	candidates := []uint16{timing.HBlankStartDot}
+	if dot, ok := sc.irqDot(v); ok {
+		candidates = append(candidates, dot)
+	}
+	for _, dot := range candidates {
+		if c := base + uint64(dot)*timing.ClocksPerDot; c > t && c < next {
+			next = c
+		}
+	}
+	return next
+}
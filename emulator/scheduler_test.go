@@ -0,0 +1,163 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes/emulator/mmio"
+	"github.com/alttpo/snes/timing"
+)
+
+func newSchedulerTest(t *testing.T, standard timing.Standard, code []byte) (*System, *Scheduler) {
+	t.Helper()
+	s := newCodeSystem(t, code)
+	if err := s.AttachMMIO(mmio.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := NewScheduler(s, standard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NMI: INC $0010; LDA $4210; RTI
+	copy(s.ROM[0x100:], []byte{0xEE, 0x10, 0x00, 0xAD, 0x10, 0x42, 0x40})
+	// IRQ: INC $0011; LDA $4211; RTI
+	copy(s.ROM[0x120:], []byte{0xEE, 0x11, 0x00, 0xAD, 0x11, 0x42, 0x40})
+	copy(s.ROM[0x7FEA:], []byte{0x00, 0x81})
+	copy(s.ROM[0x7FEE:], []byte{0x20, 0x81})
+	return s, sc
+}
+
+func TestScheduler_RunFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		standard timing.Standard
+		nmitimen byte
+		nmis     byte
+		irqs     byte
+	}{
+		{"NMI", timing.NTSC, 0x80, 3, 0},
+		{"NMI and V-IRQ", timing.NTSC, 0xA0, 3, 3},
+		{"HV-IRQ", timing.NTSC, 0x30, 0, 3},
+		{"H-IRQ", timing.NTSC, 0x10, 0, 3 * 262 % 256},
+		{"PAL NMI", timing.PAL, 0x80, 3, 0},
+	}
+	for _, tt := range tests {
+		// SEP #$20; LDA #100; STA $4209; STZ $420A; LDA #$40; STA $4207; STZ $4208; LDA #nmitimen; STA $4200;
+		// CLI; WAI; BRA -3
+		s, sc := newSchedulerTest(t, tt.standard, []byte{
+			0xE2, 0x20,
+			0xA9, 0x64, 0x8D, 0x09, 0x42, 0x9C, 0x0A, 0x42,
+			0xA9, 0x40, 0x8D, 0x07, 0x42, 0x9C, 0x08, 0x42,
+			0xA9, tt.nmitimen, 0x8D, 0x00, 0x42,
+			0x58,
+			0xCB, 0x80, 0xFD,
+		})
+		if r := sc.RunFrames(3); r.Kind != StopCycles {
+			t.Fatalf("%s: RunFrames() = %v", tt.name, r)
+		}
+		if got := sc.Frame(); got != 3 {
+			t.Errorf("%s: frame = %d, want 3", tt.name, got)
+		}
+		if got := sc.Clock(); got < 3*tt.standard.ClocksPerFrame() || got > 3*tt.standard.ClocksPerFrame()+timing.ClocksPerLine {
+			t.Errorf("%s: clock = %d after 3 frames", tt.name, got)
+		}
+		if s.WRAM[0x10] != tt.nmis {
+			t.Errorf("%s: %d NMIs, want %d", tt.name, s.WRAM[0x10], tt.nmis)
+		}
+		if s.WRAM[0x11] != tt.irqs {
+			t.Errorf("%s: %d IRQs, want %d", tt.name, s.WRAM[0x11], tt.irqs)
+		}
+	}
+}
+
+func TestScheduler_Beam(t *testing.T) {
+	// SEP #$20; loop: LDA $4212; BPL loop; LDA $2137; STP
+	s, sc := newSchedulerTest(t, timing.NTSC, []byte{
+		0xE2, 0x20,
+		0xAD, 0x12, 0x42, 0x10, 0xFB,
+		0xAD, 0x37, 0x21,
+		0xDB,
+	})
+	if r := sc.RunFrames(1); r.Kind != StopCPUStopped || r.PC != 0x00_800B {
+		t.Fatalf("RunFrames() = %v, want CPU stopped", r)
+	}
+	// the counters were latched at the start of vertical blank:
+	lo := s.Bus.EaRead(0x00_213D)
+	hi := s.Bus.EaRead(0x00_213D)
+	if v := uint16(hi&1)<<8 | uint16(lo); v != timing.NTSC.VBlankLine(false) {
+		t.Errorf("OPVCT = %d, want %d", v, timing.NTSC.VBlankLine(false))
+	}
+	if _, v := sc.Beam(); v != 225 {
+		t.Errorf("beam line = %d, want 225", v)
+	}
+}
+
+func TestScheduler_RunFrames_Breakpoints(t *testing.T) {
+	// SEP #$20; LDA #$80; STA $4200; CLI; loop: WAI; BRA loop
+	s, sc := newSchedulerTest(t, timing.NTSC, []byte{
+		0xE2, 0x20,
+		0xA9, 0x80, 0x8D, 0x00, 0x42,
+		0x58,
+		0xCB, 0x80, 0xFD,
+	})
+	d := s.Debugger()
+	handler := d.Break(BreakExecute, 0x00_8100, 1)
+	counter := d.Break(BreakWrite, 0x7E_0010, 1)
+
+	// the NMI handler's first instruction breaks, then its write to $0010:
+	want := []StopReason{
+		{Kind: StopBreakpoint, PC: 0x00_8100, Breakpoint: handler, Access: BreakExecute, Address: 0x00_8100},
+		{Kind: StopBreakpoint, PC: 0x00_8103, Breakpoint: counter, Access: BreakWrite, Address: 0x00_0010, Value: 1},
+	}
+	for i, w := range want {
+		if got := sc.RunFrames(2); got != w {
+			t.Errorf("RunFrames() #%d = %v, want %v", i, got, w)
+		}
+	}
+	if sc.Frame() != 0 {
+		t.Errorf("frame = %d, want breakpoints in the first frame", sc.Frame())
+	}
+
+	d.Clear()
+	if got := sc.RunFrames(2); got.Kind != StopCycles {
+		t.Errorf("RunFrames() without breakpoints = %v", got)
+	}
+	// frames 0 and 1 each had a vertical blank:
+	if s.WRAM[0x10] != 2 {
+		t.Errorf("%d NMIs, want 2", s.WRAM[0x10])
+	}
+}
+
+func TestScheduler_FastROM(t *testing.T) {
+	// NOP x 16 then STP, run from $00:8000 (SlowROM) and $80:8000 with MEMSEL set (FastROM)
+	code := []byte{0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xDB}
+	run := func(pc uint32, memsel byte) uint64 {
+		s, sc := newSchedulerTest(t, timing.NTSC, code)
+		s.Bus.EaWrite(0x00_420D, memsel)
+		s.SetPC(pc)
+		for !s.CPU.Stopped {
+			sc.Step()
+		}
+		return sc.Clock()
+	}
+	// NOP is an opcode fetch and an internal cycle; STP is an opcode fetch and two internal cycles:
+	if got, want := run(0x00_8000, 1), uint64(16*(timing.SlowClocks+6)+timing.SlowClocks+2*6); got != want {
+		t.Errorf("SlowROM = %d clocks, want %d", got, want)
+	}
+	if got, want := run(0x80_8000, 1), uint64(16*(timing.FastClocks+6)+timing.FastClocks+2*6); got != want {
+		t.Errorf("FastROM = %d clocks, want %d", got, want)
+	}
+	if got, want := run(0x80_8000, 0), uint64(16*(timing.SlowClocks+6)+timing.SlowClocks+2*6); got != want {
+		t.Errorf("FastROM disabled = %d clocks, want %d", got, want)
+	}
+}
+
+func TestNewScheduler_NoMMIO(t *testing.T) {
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewScheduler(s, timing.NTSC); err != ErrNoMMIO {
+		t.Errorf("NewScheduler = %v, want ErrNoMMIO", err)
+	}
+}
@@ -0,0 +1,209 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	snapshotMagic   = "SNESSNAP"
+	snapshotVersion = 3
+)
+
+var ErrSnapshotFormat = errors.New("emulator: not a snapshot")
+
+// cpuState is the fixed-size CPU part of a snapshot
+type cpuState struct {
+	PC, SP             uint16
+	RA, RX, RY         uint16
+	RAh, RAl, RXl, RYl byte
+	RDBR               byte
+	RD                 uint16
+	RK                 byte
+	N, V, M, X         byte
+	D, I, Z, C         byte
+	B, E               byte
+	Interrupt          byte
+	Stopped            byte
+	Waiting            byte
+	FastROM            byte
+	PRK                byte
+	PPC                uint16
+	WDM                byte
+	Cycles             byte
+	AllCycles          uint64
+	AllClocks          uint64
+}
+
+func (s *System) saveCPU() (c cpuState) {
+	cpu := &s.CPU
+	c = cpuState{
+		PC: cpu.PC, SP: cpu.SP,
+		RA: cpu.RA, RX: cpu.RX, RY: cpu.RY,
+		RAh: cpu.RAh, RAl: cpu.RAl, RXl: cpu.RXl, RYl: cpu.RYl,
+		RDBR: cpu.RDBR, RD: cpu.RD, RK: cpu.RK,
+		N: cpu.N, V: cpu.V, M: cpu.M, X: cpu.X,
+		D: cpu.D, I: cpu.I, Z: cpu.Z, C: cpu.C,
+		B: cpu.B, E: cpu.E,
+		Interrupt: cpu.Interrupt,
+		PRK:       cpu.PRK, PPC: cpu.PPC, WDM: cpu.WDM,
+		Cycles: cpu.Cycles, AllCycles: cpu.AllCycles, AllClocks: cpu.AllClocks,
+	}
+	if cpu.Stopped {
+		c.Stopped = 1
+	}
+	if cpu.Waiting {
+		c.Waiting = 1
+	}
+	if cpu.FastROM {
+		c.FastROM = 1
+	}
+	return
+}
+
+func (s *System) loadCPU(c *cpuState) {
+	cpu := &s.CPU
+	cpu.PC, cpu.SP = c.PC, c.SP
+	cpu.RA, cpu.RX, cpu.RY = c.RA, c.RX, c.RY
+	cpu.RAh, cpu.RAl, cpu.RXl, cpu.RYl = c.RAh, c.RAl, c.RXl, c.RYl
+	cpu.RDBR, cpu.RD, cpu.RK = c.RDBR, c.RD, c.RK
+	cpu.N, cpu.V, cpu.M, cpu.X = c.N, c.V, c.M, c.X
+	cpu.D, cpu.I, cpu.Z, cpu.C = c.D, c.I, c.Z, c.C
+	cpu.B, cpu.E = c.B, c.E
+	cpu.Interrupt = c.Interrupt
+	cpu.Stopped = c.Stopped != 0
+	cpu.Waiting = c.Waiting != 0
+	cpu.FastROM = c.FastROM != 0
+	cpu.PRK, cpu.PPC, cpu.WDM = c.PRK, c.PPC, c.WDM
+	cpu.Cycles, cpu.AllCycles, cpu.AllClocks = c.Cycles, c.AllCycles, c.AllClocks
+}
+
+// snapshot sections follow the CPU state as a 4-byte tag, a uint32 length and the data:
+var (
+	tagWRAM = [4]byte{'W', 'R', 'A', 'M'}
+	tagSRAM = [4]byte{'S', 'R', 'A', 'M'}
+	tagHWIO = [4]byte{'H', 'W', 'I', 'O'}
+)
+
+func writeSection(w io.Writer, tag [4]byte, data []byte) (err error) {
+	var hdr [8]byte
+	copy(hdr[:4], tag[:])
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(data)))
+	if _, err = w.Write(hdr[:]); err != nil {
+		return
+	}
+	_, err = w.Write(data)
+	return
+}
+
+// Snapshot writes the CPU registers and flags, cycle counters, WRAM, SRAM and hardware register state to w.
+// ROM is not included.
+func (s *System) Snapshot(w io.Writer) (err error) {
+	if _, err = io.WriteString(w, snapshotMagic); err != nil {
+		return
+	}
+	if err = binary.Write(w, binary.LittleEndian, uint16(snapshotVersion)); err != nil {
+		return
+	}
+	c := s.saveCPU()
+	if err = binary.Write(w, binary.LittleEndian, &c); err != nil {
+		return
+	}
+
+	if err = writeSection(w, tagWRAM, s.WRAM[:]); err != nil {
+		return
+	}
+	if err = writeSection(w, tagSRAM, s.SRAM); err != nil {
+		return
+	}
+	if m, ok := s.HWIO.(encoding.BinaryMarshaler); ok {
+		var data []byte
+		if data, err = m.MarshalBinary(); err != nil {
+			return
+		}
+		if err = writeSection(w, tagHWIO, data); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Restore reads a snapshot written by Snapshot. Sections unknown to this version are skipped. Active checkpoints stay
+// usable and rewind to their own state.
+func (s *System) Restore(r io.Reader) (err error) {
+	var magic [len(snapshotMagic)]byte
+	if _, err = io.ReadFull(r, magic[:]); err != nil {
+		return
+	}
+	if string(magic[:]) != snapshotMagic {
+		return ErrSnapshotFormat
+	}
+	var version uint16
+	if err = binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return
+	}
+	if version != snapshotVersion {
+		return fmt.Errorf("emulator: unsupported snapshot version %d", version)
+	}
+	var c cpuState
+	if err = binary.Read(r, binary.LittleEndian, &c); err != nil {
+		return
+	}
+
+	// read all sections before touching any state so a truncated snapshot leaves the System intact:
+	sections := make(map[[4]byte][]byte)
+	for {
+		var hdr [8]byte
+		if _, err = io.ReadFull(r, hdr[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return
+		}
+		var tag [4]byte
+		copy(tag[:], hdr[:4])
+		data := make([]byte, binary.LittleEndian.Uint32(hdr[4:]))
+		if _, err = io.ReadFull(r, data); err != nil {
+			return
+		}
+		sections[tag] = data
+	}
+	err = nil
+
+	if data, ok := sections[tagWRAM]; ok && len(data) != len(s.WRAM) {
+		return fmt.Errorf("emulator: snapshot WRAM is %d bytes, expected %d", len(data), len(s.WRAM))
+	}
+	if data, ok := sections[tagSRAM]; ok && len(data) != len(s.SRAM) {
+		return fmt.Errorf("emulator: snapshot SRAM is %d bytes, expected %d", len(data), len(s.SRAM))
+	}
+	if data, ok := sections[tagHWIO]; ok {
+		if u, ok := s.HWIO.(encoding.BinaryUnmarshaler); ok {
+			if err = u.UnmarshalBinary(data); err != nil {
+				return
+			}
+		}
+	}
+
+	s.loadCPU(&c)
+	if data, ok := sections[tagWRAM]; ok {
+		s.preserveArea(areaWRAM)
+		copy(s.WRAM[:], data)
+	}
+	if data, ok := sections[tagSRAM]; ok {
+		s.preserveArea(areaSRAM)
+		copy(s.SRAM, data)
+		s.markSRAMDirty(0, uint32(len(s.SRAM)))
+	}
+	return
+}
+
+// SnapshotBytes returns the snapshot as a byte slice
+func (s *System) SnapshotBytes() []byte {
+	var b bytes.Buffer
+	// writes to a bytes.Buffer never fail:
+	_ = s.Snapshot(&b)
+	return b.Bytes()
+}
@@ -0,0 +1,178 @@
+package emulator
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestSystem(t *testing.T) *System {
+	t.Helper()
+	// LDA #$1234; STA $7E0010; STA $700020; STA $2100; NOP
+	return newCodeSystem(t, []byte{
+		0xA9, 0x34, 0x12,
+		0x8F, 0x10, 0x00, 0x7E,
+		0x8F, 0x20, 0x00, 0x70,
+		0x8D, 0x00, 0x21,
+		0xEA,
+	})
+}
+
+func TestSystem_Snapshot(t *testing.T) {
+	s := newTestSystem(t)
+	s.Bus.EaWrite(0x7E_0000, 0x55)
+	s.Bus.EaWrite(0x70_0000, 0x66)
+	s.Bus.EaWrite(0x00_4200, 0x77)
+
+	var b bytes.Buffer
+	if err := s.Snapshot(&b); err != nil {
+		t.Fatal(err)
+	}
+	before := s.CPU
+
+	if !s.RunUntil(0x00_800E, 100) {
+		t.Fatalf("did not reach target, PC = $%06X", s.GetPC())
+	}
+	s.Bus.EaWrite(0x00_4200, 0x00)
+
+	if err := s.Restore(&b); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetPC() != 0x00_8000 || s.CPU.RA != before.RA || s.CPU.AllCycles != before.AllCycles {
+		t.Errorf("CPU not restored: PC = $%06X, A = $%04X, cycles = %d", s.GetPC(), s.CPU.RA, s.CPU.AllCycles)
+	}
+	if s.WRAM[0] != 0x55 || s.WRAM[0x10] != 0 {
+		t.Errorf("WRAM not restored: $%02X $%02X", s.WRAM[0], s.WRAM[0x10])
+	}
+	if s.SRAM[0] != 0x66 || s.SRAM[0x20] != 0 {
+		t.Errorf("SRAM not restored: $%02X $%02X", s.SRAM[0], s.SRAM[0x20])
+	}
+	if v := s.Bus.EaRead(0x00_4200); v != 0x77 {
+		t.Errorf("HWIO not restored: $%02X", v)
+	}
+}
+
+func TestSystem_Restore_Fail(t *testing.T) {
+	s := newTestSystem(t)
+	snap := s.SnapshotBytes()
+
+	if err := s.Restore(bytes.NewReader([]byte("NOTASNAPSHOT"))); err != ErrSnapshotFormat {
+		t.Errorf("Restore() error = %v, want %v", err, ErrSnapshotFormat)
+	}
+
+	s.WRAM[0] = 0x42
+	if err := s.Restore(bytes.NewReader(snap[:len(snap)-1])); err == nil {
+		t.Error("Restore() of truncated snapshot succeeded")
+	}
+	if s.WRAM[0] != 0x42 {
+		t.Error("truncated snapshot modified WRAM")
+	}
+}
+
+func TestSystem_Checkpoint(t *testing.T) {
+	s := newTestSystem(t)
+
+	cp, err := s.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !s.RunUntil(0x00_800E, 100) {
+			t.Fatalf("did not reach target, PC = $%06X", s.GetPC())
+		}
+		if s.WRAM[0x10] != 0x34 || s.SRAM[0x20] != 0x34 || s.Bus.EaRead(0x00_2100) != 0x34 {
+			t.Fatal("code did not run")
+		}
+		if err = s.Rewind(cp); err != nil {
+			t.Fatal(err)
+		}
+		if s.GetPC() != 0x00_8000 || s.WRAM[0x10] != 0 || s.SRAM[0x20] != 0 || s.Bus.EaRead(0x00_2100) != 0 {
+			t.Fatalf("iteration %d: state not rewound", i)
+		}
+	}
+
+	s.Release(cp)
+	if err = s.Rewind(cp); err != ErrCheckpointReleased {
+		t.Errorf("Rewind() error = %v, want %v", err, ErrCheckpointReleased)
+	}
+}
+
+func TestSystem_Checkpoint_Nested(t *testing.T) {
+	s := newTestSystem(t)
+
+	outer, _ := s.Checkpoint()
+	s.Bus.EaWrite(0x7E_1000, 0x01)
+	inner, _ := s.Checkpoint()
+	s.Bus.EaWrite(0x7E_1000, 0x02)
+	s.Bus.EaWrite(0x7E_5000, 0x03)
+
+	if err := s.Rewind(inner); err != nil {
+		t.Fatal(err)
+	}
+	if s.WRAM[0x1000] != 0x01 || s.WRAM[0x5000] != 0 {
+		t.Errorf("inner rewind: $%02X $%02X", s.WRAM[0x1000], s.WRAM[0x5000])
+	}
+
+	if err := s.Rewind(outer); err != nil {
+		t.Fatal(err)
+	}
+	if s.WRAM[0x1000] != 0 {
+		t.Errorf("outer rewind: $%02X", s.WRAM[0x1000])
+	}
+	if err := s.Rewind(inner); err != ErrCheckpointReleased {
+		t.Errorf("Rewind(inner) error = %v, want %v", err, ErrCheckpointReleased)
+	}
+}
+
+func TestSystem_Checkpoint_Restore(t *testing.T) {
+	s := newTestSystem(t)
+	s.Bus.EaWrite(0x7E_0000, 0x11)
+	s.Bus.EaWrite(0x7F_8000, 0x22)
+	s.Bus.EaWrite(0x70_0000, 0x33)
+	snap := s.SnapshotBytes()
+
+	s.Bus.EaWrite(0x7E_0000, 0x44)
+	s.Bus.EaWrite(0x7F_8000, 0x55)
+	s.Bus.EaWrite(0x70_0000, 0x66)
+	cp, err := s.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dirty one page through the bus, then restore over everything:
+	s.Bus.EaWrite(0x7E_0000, 0x77)
+	if err = s.Restore(bytes.NewReader(snap)); err != nil {
+		t.Fatal(err)
+	}
+	if s.WRAM[0] != 0x11 || s.WRAM[0x1_8000] != 0x22 || s.SRAM[0] != 0x33 {
+		t.Fatalf("Restore() = $%02X $%02X $%02X", s.WRAM[0], s.WRAM[0x1_8000], s.SRAM[0])
+	}
+
+	if err = s.Rewind(cp); err != nil {
+		t.Fatal(err)
+	}
+	if s.WRAM[0] != 0x44 || s.WRAM[0x1_8000] != 0x55 || s.SRAM[0] != 0x66 {
+		t.Errorf("Rewind() after Restore() = $%02X $%02X $%02X, want $44 $55 $66", s.WRAM[0], s.WRAM[0x1_8000], s.SRAM[0])
+	}
+
+	// LoadSRAM is rewound as well:
+	if _, err = s.LoadSRAM(bytes.NewReader([]byte{0x99})); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Rewind(cp); err != nil {
+		t.Fatal(err)
+	}
+	if s.SRAM[0] != 0x66 {
+		t.Errorf("Rewind() after LoadSRAM() = $%02X, want $66", s.SRAM[0])
+	}
+
+	// and so is MountSRAM:
+	if err = s.MountSRAM(&memSaveFile{data: []byte{0x99}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Rewind(cp); err != nil {
+		t.Fatal(err)
+	}
+	if s.SRAM[0] != 0x66 {
+		t.Errorf("Rewind() after MountSRAM() = $%02X, want $66", s.SRAM[0])
+	}
+}
@@ -0,0 +1,141 @@
+package emulator
+
+import (
+	"errors"
+	"io"
+
+	"github.com/alttpo/snes"
+)
+
+const sramPageSize = 0x400
+
+var ErrNoSRAM = errors.New("emulator: system has no SRAM")
+
+// SaveFile is a battery save file that SRAM can be mounted on
+type SaveFile interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// SRAMSize returns the SRAM size declared by the header; a RAM size of 0 means no SRAM
+func SRAMSize(h *snes.Header) uint32 {
+	if h.RAMSize == 0 {
+		return 0
+	}
+	if h.RAMSize > 0x0A {
+		// cap at the 1MiB of SRAM space in FX Pak Pro address space:
+		return 0x100000
+	}
+	return h.RAMSizeBytes()
+}
+
+// LoadSRAM reads a .srm file into SRAM. A file shorter than SRAM is padded with zeroes; bytes beyond the size of
+// SRAM are not read. n is the number of bytes loaded from the file. Active checkpoints rewind to the SRAM from before
+// the load.
+func (s *System) LoadSRAM(r io.Reader) (n int, err error) {
+	if len(s.SRAM) == 0 {
+		return 0, ErrNoSRAM
+	}
+	s.preserveArea(areaSRAM)
+	n, err = io.ReadFull(r, s.SRAM)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	for i := n; i < len(s.SRAM); i++ {
+		s.SRAM[i] = 0
+	}
+	s.markSRAMDirty(0, uint32(len(s.SRAM)))
+	return
+}
+
+// SaveSRAM writes SRAM as a .srm file of exactly the SRAM size
+func (s *System) SaveSRAM(w io.Writer) (err error) {
+	if len(s.SRAM) == 0 {
+		return ErrNoSRAM
+	}
+	_, err = w.Write(s.SRAM)
+	return
+}
+
+// MountSRAM loads SRAM from the save file and tracks writes through the bus until FlushSRAM writes the dirty
+// pages back. The same padding and truncation rules as LoadSRAM apply. Any previously mounted file is flushed first.
+// Active checkpoints rewind to the SRAM from before the mount.
+func (s *System) MountSRAM(f SaveFile) (err error) {
+	if len(s.SRAM) == 0 {
+		return ErrNoSRAM
+	}
+	if err = s.UnmountSRAM(); err != nil {
+		return
+	}
+
+	s.preserveArea(areaSRAM)
+	var n int
+	n, err = f.ReadAt(s.SRAM, 0)
+	if err == io.EOF {
+		err = nil
+	} else if err != nil {
+		return
+	}
+	for i := n; i < len(s.SRAM); i++ {
+		s.SRAM[i] = 0
+	}
+
+	s.sramFile = f
+	s.sramDirty = make([]bool, (len(s.SRAM)+sramPageSize-1)/sramPageSize)
+	if n < len(s.SRAM) {
+		// write out the padding on the next flush:
+		s.markSRAMDirty(uint32(n), uint32(len(s.SRAM)))
+	}
+	return
+}
+
+// SRAMDirty reports whether SRAM has writes that have not been flushed to the mounted save file
+func (s *System) SRAMDirty() bool {
+	for _, dirty := range s.sramDirty {
+		if dirty {
+			return true
+		}
+	}
+	return false
+}
+
+// FlushSRAM writes dirty SRAM pages to the mounted save file
+func (s *System) FlushSRAM() (err error) {
+	if s.sramFile == nil {
+		return nil
+	}
+	for i, dirty := range s.sramDirty {
+		if !dirty {
+			continue
+		}
+		start := i * sramPageSize
+		end := start + sramPageSize
+		if end > len(s.SRAM) {
+			end = len(s.SRAM)
+		}
+		if _, err = s.sramFile.WriteAt(s.SRAM[start:end], int64(start)); err != nil {
+			return
+		}
+		s.sramDirty[i] = false
+	}
+	return
+}
+
+// UnmountSRAM flushes and detaches the mounted save file
+func (s *System) UnmountSRAM() (err error) {
+	if err = s.FlushSRAM(); err != nil {
+		return
+	}
+	s.sramFile = nil
+	s.sramDirty = nil
+	return
+}
+
+func (s *System) markSRAMDirty(start, end uint32) {
+	if s.sramDirty == nil || start >= end {
+		return
+	}
+	for i := start / sramPageSize; i <= (end-1)/sramPageSize && int(i) < len(s.sramDirty); i++ {
+		s.sramDirty[i] = true
+	}
+}
@@ -0,0 +1,174 @@
+package emulator
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/alttpo/snes"
+)
+
+// memSaveFile is an in-memory SaveFile that records writes
+type memSaveFile struct {
+	data   []byte
+	writes int
+}
+
+func (f *memSaveFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n = copy(p, f.data[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+	return
+}
+
+func (f *memSaveFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	f.writes++
+	return copy(f.data[off:], p), nil
+}
+
+func newSRAMSystem(t *testing.T, ramSize byte) *System {
+	t.Helper()
+	rom := &snes.ROM{
+		Contents: make([]byte, 0x80000),
+		Header:   snes.Header{MapMode: 0x20, ROMSize: 0x09, RAMSize: ramSize},
+	}
+	s, err := NewSystem(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewSystem_SRAMSize(t *testing.T) {
+	if s := newSRAMSystem(t, 0x03); len(s.SRAM) != 0x2000 {
+		t.Errorf("len(SRAM) = %#x, want 0x2000", len(s.SRAM))
+	}
+	if s := newSRAMSystem(t, 0x00); s.SRAM != nil {
+		t.Errorf("len(SRAM) = %#x, want no SRAM", len(s.SRAM))
+	}
+}
+
+func TestSystem_LoadSRAM(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  []byte
+		wantN int
+		want  []byte
+	}{
+		{
+			name:  "exact",
+			file:  bytes.Repeat([]byte{0xAA}, 0x800),
+			wantN: 0x800,
+			want:  bytes.Repeat([]byte{0xAA}, 0x800),
+		},
+		{
+			name:  "short file is padded",
+			file:  bytes.Repeat([]byte{0xBB}, 0x200),
+			wantN: 0x200,
+			want:  append(bytes.Repeat([]byte{0xBB}, 0x200), make([]byte, 0x600)...),
+		},
+		{
+			name:  "long file is truncated",
+			file:  bytes.Repeat([]byte{0xCC}, 0x1000),
+			wantN: 0x800,
+			want:  bytes.Repeat([]byte{0xCC}, 0x800),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSRAMSystem(t, 0x01)
+			for i := range s.SRAM {
+				s.SRAM[i] = 0xFF
+			}
+			n, err := s.LoadSRAM(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.wantN {
+				t.Errorf("LoadSRAM() n = %#x, want %#x", n, tt.wantN)
+			}
+			if !bytes.Equal(s.SRAM, tt.want) {
+				t.Error("LoadSRAM() contents mismatch")
+			}
+
+			var b bytes.Buffer
+			if err = s.SaveSRAM(&b); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b.Bytes(), tt.want) {
+				t.Errorf("SaveSRAM() wrote %#x bytes, want %#x", b.Len(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSystem_LoadSRAM_NoSRAM(t *testing.T) {
+	s := newSRAMSystem(t, 0x00)
+	if _, err := s.LoadSRAM(bytes.NewReader([]byte{1})); err != ErrNoSRAM {
+		t.Errorf("LoadSRAM() error = %v, want %v", err, ErrNoSRAM)
+	}
+	if err := s.MountSRAM(&memSaveFile{}); err != ErrNoSRAM {
+		t.Errorf("MountSRAM() error = %v, want %v", err, ErrNoSRAM)
+	}
+}
+
+func TestSystem_MountSRAM(t *testing.T) {
+	s := newSRAMSystem(t, 0x03)
+	f := &memSaveFile{data: bytes.Repeat([]byte{0x11}, 0x2000)}
+	if err := s.MountSRAM(f); err != nil {
+		t.Fatal(err)
+	}
+	if s.SRAM[0x1FFF] != 0x11 {
+		t.Fatal("MountSRAM() did not load the save")
+	}
+	if s.SRAMDirty() {
+		t.Error("SRAM dirty after mount")
+	}
+
+	// round-trip a write through the bus:
+	s.Bus.EaWrite(0x70_0123, 0x22)
+	if !s.SRAMDirty() {
+		t.Fatal("SRAM not dirty after write")
+	}
+	if err := s.FlushSRAM(); err != nil {
+		t.Fatal(err)
+	}
+	if f.data[0x123] != 0x22 {
+		t.Errorf("save file not updated: $%02X", f.data[0x123])
+	}
+	if f.writes != 1 {
+		t.Errorf("writes = %d, want only the dirty page", f.writes)
+	}
+	if s.SRAMDirty() {
+		t.Error("SRAM dirty after flush")
+	}
+
+	s.Bus.EaWrite(0x70_1800, 0x33)
+	if err := s.UnmountSRAM(); err != nil {
+		t.Fatal(err)
+	}
+	if f.data[0x1800] != 0x33 {
+		t.Error("UnmountSRAM() did not flush")
+	}
+}
+
+func TestSystem_MountSRAM_Short(t *testing.T) {
+	s := newSRAMSystem(t, 0x01)
+	f := &memSaveFile{data: []byte{1, 2, 3}}
+	if err := s.MountSRAM(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FlushSRAM(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.data) != 0x800 || f.data[2] != 3 {
+		t.Errorf("save file not padded to SRAM size: %#x bytes", len(f.data))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/alttpo/snes/emulator/bus"
 	"github.com/alttpo/snes/emulator/cpu65c816"
 	"github.com/alttpo/snes/emulator/memory"
+	"github.com/alttpo/snes/mapping"
 	"io"
 )
 
@@ -12,11 +13,29 @@ type System struct {
 	Bus bus.Bus
 	CPU cpu65c816.CPU
 
+	// Mapper is the cartridge mapping the bus was built from; nil for CreateEmulator
+	Mapper mapping.Mapper
+
 	ROM  [0x1000000]byte
 	WRAM [0x20000]byte
-	SRAM [0x10000]byte
+	// SRAM is sized from the header by NewSystem; CreateEmulator allocates 64KiB when it is nil
+	SRAM []byte
+
+	// HWIO is the memory-mapped I/O handler; its state is included in snapshots when it implements
+	// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+	HWIO memory.Memory
 
 	Logger io.Writer
+
+	checkpoints []*Checkpoint
+
+	sramFile  SaveFile
+	sramDirty []bool
+
+	pendingClocks uint64
+
+	debugger *Debugger
+	tracer   *tracer
 }
 
 type Committer interface {
@@ -57,12 +76,16 @@ func (s *System) CreateEmulator() (err error) {
 		}
 	}
 
+	if s.SRAM == nil {
+		s.SRAM = make([]byte, 0x10000)
+	}
+
 	// SRAM (banks 70-7D,F0-FF) (7E,7F) will be overwritten with WRAM:
 	for b := uint32(0); b < uint32(len(s.SRAM)>>15); b++ {
 		bank := b << 16
 		halfBank := b << 15
 		err = s.Bus.Attach(
-			memory.NewRAM(s.SRAM[halfBank:halfBank+0x8000], bank+0x70_0000),
+			s.newRAM(areaSRAM, halfBank, halfBank+0x8000, bank+0x70_0000),
 			"sram",
 			bank+0x70_0000,
 			bank+0x70_7FFF,
@@ -73,7 +96,7 @@ func (s *System) CreateEmulator() (err error) {
 
 		// mirror:
 		err = s.Bus.Attach(
-			memory.NewRAM(s.SRAM[halfBank:halfBank+0x8000], bank+0xF0_0000),
+			s.newRAM(areaSRAM, halfBank, halfBank+0x8000, bank+0xF0_0000),
 			"sram",
 			bank+0xF0_0000,
 			bank+0xF0_7FFF,
@@ -86,7 +109,7 @@ func (s *System) CreateEmulator() (err error) {
 	// WRAM:
 	{
 		err = s.Bus.Attach(
-			memory.NewRAM(s.WRAM[0:0x20000], 0x7E0000),
+			s.newRAM(areaWRAM, 0, 0x20000, 0x7E0000),
 			"wram",
 			0x7E_0000,
 			0x7F_FFFF,
@@ -99,7 +122,7 @@ func (s *System) CreateEmulator() (err error) {
 		for b := uint32(0); b < 0x40; b++ {
 			bank := b << 16
 			err = s.Bus.Attach(
-				memory.NewRAM(s.WRAM[0:0x2000], bank),
+				s.newRAM(areaWRAM, 0, 0x2000, bank),
 				"wram",
 				bank,
 				bank|0x1FFF,
@@ -111,7 +134,7 @@ func (s *System) CreateEmulator() (err error) {
 		for b := uint32(0x80); b < 0xC0; b++ {
 			bank := b << 16
 			err = s.Bus.Attach(
-				memory.NewRAM(s.WRAM[0:0x2000], bank),
+				s.newRAM(areaWRAM, 0, 0x2000, bank),
 				"wram",
 				bank,
 				bank|0x1FFF,
@@ -125,6 +148,7 @@ func (s *System) CreateEmulator() (err error) {
 	// Memory-mapped IO registers:
 	{
 		hwio := &memory.FakeHW{}
+		s.HWIO = hwio
 		for b := uint32(0); b < 0x70; b++ {
 			bank := b << 16
 			err = s.Bus.Attach(
@@ -162,7 +186,14 @@ func (s *System) GetPC() uint32 {
 	return uint32(s.CPU.RK)<<16 | uint32(s.CPU.PC)
 }
 
+// RunUntil steps the CPU until it reaches targetPC or maxCycles elapse and reports whether it reached targetPC
 func (s *System) RunUntil(targetPC uint32, maxCycles uint64) bool {
+	return s.Run(targetPC, maxCycles).Kind == StopTarget
+}
+
+// Run steps the CPU until it reaches targetPC, a breakpoint fires, the CPU executes STP or maxCycles elapse.
+// Execution breakpoints at the starting PC are skipped so that Run can resume from one.
+func (s *System) Run(targetPC uint32, maxCycles uint64) (reason StopReason) {
 	// reserve space in the logger for enough room up to maxCycles if reasonable:
 	if reserver, ok := s.Logger.(Reserver); ok {
 		n := int(maxCycles)
@@ -174,17 +205,35 @@ func (s *System) RunUntil(targetPC uint32, maxCycles uint64) bool {
 
 	var oa [100]byte
 
+	s.clearAccessBreak()
+	reason.Kind = StopCycles
 	for cycles := uint64(0); cycles < maxCycles; {
 		if s.Logger != nil {
 			o := oa[:0]
 			o = s.CPU.DisassembleCurrentPC(o)
 			_, _ = s.Logger.Write(o)
 		}
-		if s.GetPC() == targetPC {
+		pc := s.GetPC()
+		if pc == targetPC {
+			reason.Kind = StopTarget
 			break
 		}
-		nCycles, _ := s.CPU.Step()
+		if cycles != 0 {
+			if r, ok := s.executeBreak(); ok {
+				reason = r
+				break
+			}
+		}
+		nCycles, stopped := s.step()
 		cycles += uint64(nCycles)
+		if r, ok := s.accessBreak(); ok {
+			reason = r
+			break
+		}
+		if stopped {
+			reason.Kind = StopCPUStopped
+			break
+		}
 	}
 
 	// commit to the logger:
@@ -192,5 +241,9 @@ func (s *System) RunUntil(targetPC uint32, maxCycles uint64) bool {
 		committer.Commit()
 	}
 
-	return s.GetPC() == targetPC
+	if reason.Kind == StopCycles && s.GetPC() == targetPC {
+		reason.Kind = StopTarget
+	}
+	reason.PC = s.GetPC()
+	return
 }
@@ -1,9 +1,25 @@
 package emulator
 
 import (
+	"bytes"
+	"io"
 	"testing"
+
+	"github.com/alttpo/snes/emulator/bus"
 )
 
+// newCodeSystem creates an emulator with code at the start of ROM and the PC pointing to it
+func newCodeSystem(t *testing.T, code []byte) *System {
+	t.Helper()
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	copy(s.ROM[0:], code)
+	s.SetPC(0x00_8000)
+	return s
+}
+
 func TestSystem_CreateEmulator(t *testing.T) {
 	// verify our ROM, SRAM, WRAM mappings in the bus:
 	tests := []struct {
@@ -130,3 +146,23 @@ func TestSystem_CreateEmulator(t *testing.T) {
 		})
 	}
 }
+
+func TestSystem_Run_LoggerIsNotObserved(t *testing.T) {
+	count := func(logger io.Writer) int {
+		s := newDebuggerTest(t)
+		s.Logger = logger
+		n := 0
+		s.Bus.Observe(bus.All, 0x00_0000, 0xFF_FFFF, func(uint32, byte, bus.Access) { n++ })
+		if r := s.Run(0xFF_FFFF, 1000); r.Kind != StopCPUStopped {
+			t.Fatalf("Run() = %v", r)
+		}
+		return n
+	}
+	var log bytes.Buffer
+	if with, without := count(&log), count(nil); with != without {
+		t.Errorf("%d bus accesses with Logger, %d without", with, without)
+	}
+	if log.Len() == 0 {
+		t.Error("nothing logged")
+	}
+}
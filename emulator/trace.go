@@ -0,0 +1,108 @@
+package emulator
+
+import (
+	"errors"
+	"io"
+
+	"github.com/alttpo/snes/emulator/cpu65c816"
+	"github.com/alttpo/snes/emulator/trace"
+)
+
+var ErrNotTracing = errors.New("emulator: no trace is being recorded")
+
+// tracer builds a trace.Record for the instruction being stepped
+type tracer struct {
+	w   *trace.Writer
+	rec trace.Record
+	err error
+}
+
+// StartTrace records every instruction executed by Run and Scheduler.Step to w as a binary trace; see package
+// trace to read it back. A trace already being recorded is stopped first.
+func (s *System) StartTrace(w io.Writer) (err error) {
+	if s.tracer != nil {
+		if err = s.StopTrace(); err != nil {
+			return
+		}
+	}
+	t := &tracer{}
+	if t.w, err = trace.NewWriter(w); err != nil {
+		return
+	}
+	s.tracer = t
+	s.CPU.OnAccess = s.onAccess
+	return
+}
+
+// StopTrace flushes the trace and stops recording. It returns the first error met while writing records.
+func (s *System) StopTrace() error {
+	t := s.tracer
+	if t == nil {
+		return ErrNotTracing
+	}
+	s.tracer = nil
+	if s.debugger == nil {
+		s.CPU.OnAccess = nil
+	}
+	if err := t.w.Flush(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// onAccess dispatches CPU bus accesses to the debugger and the tracer
+func (s *System) onAccess(ea uint32, value byte, kind cpu65c816.AccessKind) {
+	if s.debugger != nil {
+		s.debugger.onAccess(ea, value, kind)
+	}
+	if t := s.tracer; t != nil && kind != cpu65c816.AccessFetch {
+		t.rec.Accesses = append(t.rec.Accesses, trace.Access{Address: ea & 0xFFFFFF, Value: value, Write: kind == cpu65c816.AccessWrite})
+	}
+}
+
+// step executes one CPU instruction, recording it when tracing. Steps that enter an interrupt handler or idle in WAI
+// execute no instruction and are not recorded; the handler's instructions are recorded as they run.
+func (s *System) step() (cycles int, stopped bool) {
+	t := s.tracer
+	if t == nil || s.CPU.InterruptPending() || s.CPU.Waiting {
+		return s.CPU.Step()
+	}
+
+	cpu := &s.CPU
+	r := &t.rec
+	r.PC = uint32(cpu.RK)<<16 | uint32(cpu.PC)
+	// EaDump reads without notifying bus observers:
+	var op [4]byte
+	s.Bus.EaDump(r.PC, r.PC, op[:1])
+	n := cpu65c816.InstructionSize(op[0], cpu.M, cpu.X)
+	for i := 1; i < n; i++ {
+		a := r.PC&0xFF0000 | uint32(cpu.PC+uint16(i))
+		s.Bus.EaDump(a, a, op[i:i+1])
+	}
+	r.Op = append(r.Op[:0], op[:n]...)
+	r.A = RegA.Value(cpu)
+	r.X = RegX.Value(cpu)
+	r.Y = RegY.Value(cpu)
+	r.S = cpu.SP
+	r.D = cpu.RD
+	r.DB = cpu.RDBR
+	r.P = cpu.Flags()
+	r.Flags = 0
+	if cpu.E != 0 {
+		r.Flags |= trace.FlagE
+	}
+	r.Clock = cpu.AllClocks
+	r.Accesses = r.Accesses[:0]
+
+	cycles, stopped = cpu.Step()
+
+	r.EA = 0
+	if ea, ok := cpu.EffectiveAddress(); ok {
+		r.EA = ea
+		r.Flags |= trace.FlagEA
+	}
+	if err := t.w.Write(r); err != nil && t.err == nil {
+		t.err = err
+	}
+	return
+}
@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alttpo/snes/emulator/cpu65c816"
+	"github.com/alttpo/snes/timing"
+)
+
+// Format selects how records are rendered as text
+type Format uint8
+
+const (
+	// FormatText lists the instruction bytes, registers and every data access
+	FormatText Format = iota
+	// FormatBSNES follows the bsnes-plus CPU trace log layout
+	FormatBSNES
+	// FormatMesen follows the Mesen-S default trace log layout
+	FormatMesen
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatText:
+		return "text"
+	case FormatBSNES:
+		return "bsnes"
+	case FormatMesen:
+		return "mesen"
+	default:
+		return "unknown"
+	}
+}
+
+// flags renders P as letters, upper case when set
+func flags(p byte) string {
+	const names = "nvmxdizc"
+	var b [8]byte
+	for i := 0; i < 8; i++ {
+		c := names[i]
+		if p&(0x80>>uint(i)) != 0 {
+			c -= 'a' - 'A'
+		}
+		b[i] = c
+	}
+	return string(b[:])
+}
+
+// beam returns the scanline and master clock within the line at clock
+func beam(clock uint64, std timing.Standard) (v, h uint64) {
+	t := clock % std.ClocksPerFrame()
+	return t / timing.ClocksPerLine, t % timing.ClocksPerLine
+}
+
+// Append renders the record as one line in the format and appends it to b
+func Append(b []byte, r *Record, f Format, std timing.Standard) []byte {
+	dis := cpu65c816.Disassemble(r.PC, r.Op, r.MFlag(), r.XFlag())
+	v, h := beam(r.Clock, std)
+
+	switch f {
+	case FormatBSNES:
+		if r.HasEA() {
+			dis += fmt.Sprintf(" [%06x]", r.EA)
+		}
+		b = append(b, fmt.Sprintf(
+			"%06x %-28s A:%04x X:%04x Y:%04x S:%04x D:%04x DB:%02x %s V:%3d H:%4d\n",
+			r.PC, dis, r.A, r.X, r.Y, r.S, r.D, r.DB, flags(r.P), v, h,
+		)...)
+
+	case FormatMesen:
+		dis = strings.ToUpper(dis)
+		if r.HasEA() {
+			dis += fmt.Sprintf(" [%06X]", r.EA)
+			for _, a := range r.Accesses {
+				if a.Address == r.EA {
+					dis += fmt.Sprintf(" = $%02X", a.Value)
+					break
+				}
+			}
+		}
+		b = append(b, fmt.Sprintf(
+			"%06X  %-48s A:%04X X:%04X Y:%04X S:%04X D:%04X DB:%02X P:%02X V:%-3d H:%d\n",
+			r.PC, dis, r.A, r.X, r.Y, r.S, r.D, r.DB, r.P, v, h/timing.ClocksPerDot,
+		)...)
+
+	default:
+		var op strings.Builder
+		for i, c := range r.Op {
+			if i > 0 {
+				op.WriteByte(' ')
+			}
+			fmt.Fprintf(&op, "%02X", c)
+		}
+		p := flags(r.P)
+		if r.E() {
+			p += " E"
+		}
+		b = append(b, fmt.Sprintf(
+			"%02X:%04X  %-11s  %-18s A:%04X X:%04X Y:%04X S:%04X D:%04X DB:%02X P:%s",
+			r.PC>>16, r.PC&0xFFFF, op.String(), dis, r.A, r.X, r.Y, r.S, r.D, r.DB, p,
+		)...)
+		for _, a := range r.Accesses {
+			kind := 'r'
+			if a.Write {
+				kind = 'w'
+			}
+			b = append(b, fmt.Sprintf(" %c:%06X=%02X", kind, a.Address, a.Value)...)
+		}
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// Export renders every remaining record of the trace to w in the format
+func Export(w io.Writer, r *Reader, f Format, std timing.Standard) error {
+	var rec Record
+	var line []byte
+	for {
+		if err := r.Read(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		line = Append(line[:0], &rec, f, std)
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+}
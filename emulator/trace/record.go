@@ -0,0 +1,194 @@
+// Package trace records the instructions the emulated CPU executes as compact binary records and renders them as
+// text or in the trace log formats of bsnes and Mesen.
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	magic   = "SNESTRCE"
+	version = 1
+)
+
+var ErrFormat = errors.New("trace: not a trace")
+
+// Flag bits of Record.Flags
+const (
+	FlagE  = 1 << iota // emulation mode
+	FlagEA             // EA is valid
+)
+
+// Access is a data read or write made by an instruction
+type Access struct {
+	Address uint32
+	Value   byte
+	Write   bool
+}
+
+// Record is the state of the CPU before an instruction executes and the memory it accessed
+type Record struct {
+	PC    uint32
+	Op    []byte // opcode and operand bytes
+	A     uint16
+	X, Y  uint16
+	S     uint16
+	D     uint16
+	DB    byte
+	P     byte
+	Flags byte
+	EA    uint32
+	// Clock is the master clock count before the instruction
+	Clock uint64
+
+	Accesses []Access
+}
+
+// E reports whether the CPU was in emulation mode
+func (r *Record) E() bool { return r.Flags&FlagE != 0 }
+
+// HasEA reports whether the instruction had an effective address
+func (r *Record) HasEA() bool { return r.Flags&FlagEA != 0 }
+
+// MFlag returns the accumulator width flag from P
+func (r *Record) MFlag() byte { return r.P >> 5 & 1 }
+
+// XFlag returns the index width flag from P
+func (r *Record) XFlag() byte { return r.P >> 4 & 1 }
+
+func append24(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16))
+}
+
+func append16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+// AppendBinary appends the record's binary encoding to b
+func (r *Record) AppendBinary(b []byte) []byte {
+	b = append24(b, r.PC)
+	b = append(b, byte(len(r.Op)))
+	b = append(b, r.Op...)
+	b = append16(b, r.A)
+	b = append16(b, r.X)
+	b = append16(b, r.Y)
+	b = append16(b, r.S)
+	b = append16(b, r.D)
+	b = append(b, r.DB, r.P, r.Flags)
+	b = append24(b, r.EA)
+
+	var v [binary.MaxVarintLen64]byte
+	b = append(b, v[:binary.PutUvarint(v[:], r.Clock)]...)
+	b = append(b, v[:binary.PutUvarint(v[:], uint64(len(r.Accesses)))]...)
+	for _, a := range r.Accesses {
+		addr := a.Address & 0xFFFFFF
+		if a.Write {
+			addr |= 0x1000000
+		}
+		b = append(b, byte(addr), byte(addr>>8), byte(addr>>16), byte(addr>>24), a.Value)
+	}
+	return b
+}
+
+// Writer writes trace records to a stream
+type Writer struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriter writes the trace header to w and returns a Writer for the records
+func NewWriter(w io.Writer) (*Writer, error) {
+	tw := &Writer{w: bufio.NewWriter(w)}
+	if _, err := tw.w.WriteString(magic); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(tw.w, binary.LittleEndian, uint16(version)); err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+// Write appends a record to the trace
+func (w *Writer) Write(r *Record) error {
+	w.buf = r.AppendBinary(w.buf[:0])
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// Flush writes buffered records to the underlying writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads trace records written by Writer
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the trace header and returns a Reader for the records
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var hdr [len(magic) + 2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, ErrFormat
+	}
+	if string(hdr[:len(magic)]) != magic || binary.LittleEndian.Uint16(hdr[len(magic):]) != version {
+		return nil, ErrFormat
+	}
+	return &Reader{r: br}, nil
+}
+
+// Read decodes the next record into rec, reusing its slices; it returns io.EOF after the last record
+func (r *Reader) Read(rec *Record) (err error) {
+	var fixed [3 + 1]byte
+	if _, err = io.ReadFull(r.r, fixed[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrFormat
+		}
+		return
+	}
+	rec.PC = uint32(fixed[0]) | uint32(fixed[1])<<8 | uint32(fixed[2])<<16
+	n := int(fixed[3])
+	if n > 4 {
+		return ErrFormat
+	}
+
+	var regs [4 + 10 + 3 + 3]byte
+	body := regs[:n+10+3+3]
+	if _, err = io.ReadFull(r.r, body); err != nil {
+		return ErrFormat
+	}
+	rec.Op = append(rec.Op[:0], body[:n]...)
+	body = body[n:]
+	rec.A = binary.LittleEndian.Uint16(body[0:])
+	rec.X = binary.LittleEndian.Uint16(body[2:])
+	rec.Y = binary.LittleEndian.Uint16(body[4:])
+	rec.S = binary.LittleEndian.Uint16(body[6:])
+	rec.D = binary.LittleEndian.Uint16(body[8:])
+	rec.DB, rec.P, rec.Flags = body[10], body[11], body[12]
+	rec.EA = uint32(body[13]) | uint32(body[14])<<8 | uint32(body[15])<<16
+
+	if rec.Clock, err = binary.ReadUvarint(r.r); err != nil {
+		return ErrFormat
+	}
+	var count uint64
+	if count, err = binary.ReadUvarint(r.r); err != nil || count > 0x10000 {
+		return ErrFormat
+	}
+	rec.Accesses = rec.Accesses[:0]
+	for i := uint64(0); i < count; i++ {
+		var a [5]byte
+		if _, err = io.ReadFull(r.r, a[:]); err != nil {
+			return ErrFormat
+		}
+		rec.Accesses = append(rec.Accesses, Access{
+			Address: uint32(a[0]) | uint32(a[1])<<8 | uint32(a[2])<<16,
+			Write:   a[3]&1 != 0,
+			Value:   a[4],
+		})
+	}
+	return nil
+}
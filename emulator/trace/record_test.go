@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/alttpo/snes/timing"
+)
+
+var testRecords = []Record{
+	{
+		PC: 0x00_8000, Op: []byte{0xE2, 0x20},
+		A: 0x1234, X: 0x0000, Y: 0x0000, S: 0x01FF, D: 0x0000, DB: 0x00, P: 0x04,
+		Clock: 0,
+	},
+	{
+		PC: 0x00_8007, Op: []byte{0xAF, 0x00, 0x01, 0x7E},
+		A: 0x0005, X: 0x0010, Y: 0x0020, S: 0x01FF, D: 0x0000, DB: 0x7F, P: 0x24,
+		Flags: FlagEA, EA: 0x7E_0100,
+		Clock:    timing.ClocksPerLine*2 + 40,
+		Accesses: []Access{{Address: 0x7E_0100, Value: 0x05}},
+	},
+	{
+		PC: 0x80_900C, Op: []byte{0x8D, 0x00, 0x01},
+		A: 0x0004, X: 0x0010, Y: 0x0020, S: 0x01FD, D: 0x0100, DB: 0x00, P: 0x34,
+		Flags: FlagE | FlagEA, EA: 0x00_0100,
+		Clock:    timing.NTSC.ClocksPerFrame() + 100,
+		Accesses: []Access{{Address: 0x00_0100, Value: 0x04, Write: true}},
+	},
+}
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range testRecords {
+		if err = w.Write(&testRecords[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec Record
+	for i := range testRecords {
+		if err = r.Read(&rec); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		want := testRecords[i]
+		if len(rec.Accesses) == 0 {
+			rec.Accesses = nil
+		}
+		if !reflect.DeepEqual(rec, want) {
+			t.Errorf("record %d = %+v, want %+v", i, rec, want)
+		}
+	}
+	if err = r.Read(&rec); err != io.EOF {
+		t.Errorf("Read() after last record = %v, want io.EOF", err)
+	}
+
+	// truncated traces are malformed:
+	r, _ = NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	for err == io.EOF || err == nil {
+		err = r.Read(&rec)
+	}
+	if err != ErrFormat {
+		t.Errorf("Read() of truncated trace = %v, want ErrFormat", err)
+	}
+
+	if _, err = NewReader(bytes.NewReader([]byte("SNESTRACE"))); err != ErrFormat {
+		t.Errorf("NewReader() of bad header = %v, want ErrFormat", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			FormatText,
+			"00:8000  E2 20        sep #$20           A:1234 X:0000 Y:0000 S:01FF D:0000 DB:00 P:nvmxdIzc\n" +
+				"00:8007  AF 00 01 7E  lda $7e0100        A:0005 X:0010 Y:0020 S:01FF D:0000 DB:7F P:nvMxdIzc r:7E0100=05\n" +
+				"80:900C  8D 00 01     sta $0100          A:0004 X:0010 Y:0020 S:01FD D:0100 DB:00 P:nvMXdIzc E w:000100=04\n",
+		},
+		{
+			FormatBSNES,
+			"008000 sep #$20                     A:1234 X:0000 Y:0000 S:01ff D:0000 DB:00 nvmxdIzc V:  0 H:   0\n" +
+				"008007 lda $7e0100 [7e0100]         A:0005 X:0010 Y:0020 S:01ff D:0000 DB:7f nvMxdIzc V:  2 H:  40\n" +
+				"80900c sta $0100 [000100]           A:0004 X:0010 Y:0020 S:01fd D:0100 DB:00 nvMXdIzc V:  0 H: 100\n",
+		},
+		{
+			FormatMesen,
+			"008000  SEP #$20                                         A:1234 X:0000 Y:0000 S:01FF D:0000 DB:00 P:04 V:0   H:0\n" +
+				"008007  LDA $7E0100 [7E0100] = $05                       A:0005 X:0010 Y:0020 S:01FF D:0000 DB:7F P:24 V:2   H:10\n" +
+				"80900C  STA $0100 [000100] = $04                         A:0004 X:0010 Y:0020 S:01FD D:0100 DB:00 P:34 V:0   H:25\n",
+		},
+	}
+
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf)
+	for i := range testRecords {
+		_ = w.Write(&testRecords[i])
+	}
+	_ = w.Flush()
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err = Export(&out, r, tt.format, timing.NTSC); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("Export() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package emulator
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/alttpo/snes/emulator/trace"
+)
+
+func TestSystem_Trace(t *testing.T) {
+	s := newDebuggerTest(t)
+	s.Debugger().Break(BreakWrite, 0x7E_0100, 1).Condition = RegisterMasked(RegA, 0xFF, 2)
+
+	var buf bytes.Buffer
+	if err := s.StartTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if reason := s.Run(0xFFFFFF, 1000); reason.Kind != StopBreakpoint {
+		t.Fatalf("Run() = %v, want breakpoint", reason)
+	}
+	if err := s.StopTrace(); err != nil {
+		t.Fatal(err)
+	}
+	if s.CPU.OnAccess == nil {
+		t.Error("StopTrace() removed the debugger's access hook")
+	}
+	if err := s.StopTrace(); err != ErrNotTracing {
+		t.Errorf("StopTrace() twice = %v, want ErrNotTracing", err)
+	}
+
+	r, err := trace.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pcs []uint32
+	var rec trace.Record
+	for {
+		if err = r.Read(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		pcs = append(pcs, rec.PC)
+
+		switch len(pcs) {
+		case 1:
+			if !bytes.Equal(rec.Op, []byte{0xE2, 0x20}) || rec.P&0x20 != 0 || rec.HasEA() || rec.Clock != 0 {
+				t.Errorf("SEP record = %+v", rec)
+			}
+		case 4:
+			want := []trace.Access{{Address: 0x7E_0100, Value: 5}}
+			if rec.EA != 0x7E_0100 || !rec.HasEA() || !reflect.DeepEqual(rec.Accesses, want) {
+				t.Errorf("LDA record = %+v", rec)
+			}
+			if rec.A != 0x0005 || rec.MFlag() != 1 {
+				t.Errorf("LDA record A = %04X, M = %d", rec.A, rec.MFlag())
+			}
+		case 6:
+			want := []trace.Access{{Address: 0x00_0100, Value: 4, Write: true}}
+			if rec.EA != 0x00_0100 || !reflect.DeepEqual(rec.Accesses, want) {
+				t.Errorf("STA record = %+v", rec)
+			}
+		}
+	}
+
+	// SEP, LDA, STA, then the loop until the write of 2 breaks:
+	want := []uint32{
+		0x8000, 0x8002, 0x8004,
+		0x8007, 0x800B, 0x800C, 0x800F,
+		0x8007, 0x800B, 0x800C, 0x800F,
+		0x8007, 0x800B, 0x800C,
+	}
+	if !reflect.DeepEqual(pcs, want) {
+		t.Errorf("traced PCs = %X, want %X", pcs, want)
+	}
+}
+
+func TestSystem_Trace_Interrupt(t *testing.T) {
+	// SEP #$20; WAI; LDA #$01; STP
+	s := newDebuggerTest(t)
+	copy(s.ROM[0:], []byte{0xE2, 0x20, 0xCB, 0xA9, 0x01, 0xDB})
+	// NMI handler at $00:8100: LDA $7E0100; RTI
+	copy(s.ROM[0x0100:], []byte{0xAF, 0x00, 0x01, 0x7E, 0x40})
+	copy(s.ROM[0x7FEA:], []byte{0x00, 0x81})
+	s.WRAM[0x100] = 0x42
+
+	var buf bytes.Buffer
+	if err := s.StartTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// idle in WAI, then take the NMI:
+	s.Run(0xFF_FFFF, 20)
+	if !s.CPU.Waiting {
+		t.Fatalf("CPU is not waiting, PC = $%06X", s.GetPC())
+	}
+	s.CPU.TriggerNMI()
+	if r := s.Run(0x00_8005, 100); r.Kind != StopTarget {
+		t.Fatalf("Run() = %v", r)
+	}
+	if err := s.StopTrace(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := trace.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pcs []uint32
+	var rec trace.Record
+	for {
+		if err = r.Read(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		pcs = append(pcs, rec.PC)
+		if rec.PC == 0x00_8100 {
+			want := []trace.Access{{Address: 0x7E_0100, Value: 0x42}}
+			if !bytes.Equal(rec.Op, []byte{0xAF, 0x00, 0x01, 0x7E}) || !reflect.DeepEqual(rec.Accesses, want) {
+				t.Errorf("handler record = %+v", rec)
+			}
+			if rec.P&0x04 == 0 {
+				t.Errorf("handler record P = %02X, want I set", rec.P)
+			}
+		}
+	}
+
+	// no records for the idle steps in WAI or for the interrupt entry:
+	want := []uint32{0x00_8000, 0x00_8002, 0x00_8100, 0x00_8104, 0x00_8003}
+	if !reflect.DeepEqual(pcs, want) {
+		t.Errorf("traced PCs = %X, want %X", pcs, want)
+	}
+}
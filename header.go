@@ -216,7 +216,7 @@ func (h *Header) Score(addr uint32) (score int) {
 		score += 2
 	}
 
-	// TODO: could read RESET vector opcode to increase confidence
+	// NOTE: ROM.scoreHeaderAt also checks the RESET vector opcode and the ROM size against the file size
 
 	return
 }
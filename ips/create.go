@@ -0,0 +1,128 @@
+package ips
+
+import (
+	"fmt"
+
+	"github.com/alttpo/snes"
+)
+
+const (
+	// recordOverhead is the size of a record's offset and size fields:
+	recordOverhead = 5
+	// rleSize is the encoded size of an RLE record:
+	rleSize = 8
+	// minSplitRun is the shortest run of a repeated byte worth splitting out of a record into an RLE record:
+	minSplitRun = rleSize + recordOverhead + 1
+	// minRun is the shortest run of a repeated byte worth encoding as an RLE record on its own:
+	minRun = rleSize - recordOverhead + 1
+)
+
+// Create diffs source against target and returns a minimal patch that turns source into target
+func Create(source, target []byte) (p *Patch, err error) {
+	if len(target) > maxSize {
+		return nil, fmt.Errorf("%w: target size %x", ErrOutOfBounds, len(target))
+	}
+
+	differs := func(i int) bool {
+		return i >= len(source) || source[i] != target[i]
+	}
+
+	p = &Patch{}
+	for i := 0; i < len(target); {
+		if !differs(i) {
+			i++
+			continue
+		}
+
+		// the EOF marker offset cannot start a record so include the preceding byte:
+		start := i
+		if start == eofOffset {
+			start--
+		}
+
+		// extend the record over differing bytes and bridge short runs of equal bytes that would cost more to
+		// skip over with a new record:
+		end := i
+		for end < len(target) && end-start < 0xFFFF {
+			if differs(end) {
+				end++
+				continue
+			}
+			j := end
+			for j < len(target) && !differs(j) && j-end < recordOverhead {
+				j++
+			}
+			if j >= len(target) || !differs(j) || j-start >= 0xFFFF {
+				break
+			}
+			end = j
+		}
+
+		p.appendRuns(start, target[start:end])
+		i = end
+	}
+
+	if len(target) < len(source) {
+		p.HasTruncate = true
+		p.Truncate = uint32(len(target))
+	}
+
+	return
+}
+
+// CreateFromROMs diffs the contents of two ROMs
+func CreateFromROMs(source, target *snes.ROM) (*Patch, error) {
+	return Create(source.Contents, target.Contents)
+}
+
+// appendRuns appends data as records, splitting out long runs of repeated bytes as RLE records
+func (p *Patch) appendRuns(offset int, data []byte) {
+	lit := 0
+	for i := 0; i < len(data); {
+		j := i + 1
+		for j < len(data) && data[j] == data[i] {
+			j++
+		}
+
+		run := j - i
+		whole := i == 0 && j == len(data)
+		if (whole && run >= minRun) || run >= minSplitRun {
+			start := offset + i
+			if start == eofOffset {
+				// cannot start an RLE record at the EOF marker offset; leave the first byte as a literal:
+				i++
+				start++
+			}
+			if lit < i {
+				p.appendLiteral(offset+lit, data[lit:i])
+			}
+			p.Records = append(p.Records, Record{
+				Offset: uint32(start),
+				Data:   append([]byte(nil), data[i:j]...),
+				RLE:    true,
+			})
+			lit = j
+		}
+		i = j
+	}
+	if lit < len(data) {
+		p.appendLiteral(offset+lit, data[lit:])
+	}
+}
+
+func (p *Patch) appendLiteral(offset int, data []byte) {
+	if offset == eofOffset && len(p.Records) > 0 {
+		// the previous record is adjacent; move its last byte into this record so it starts before the EOF marker:
+		last := &p.Records[len(p.Records)-1]
+		if int(last.end()) == offset && len(last.Data) > 1 {
+			b := last.Data[len(last.Data)-1]
+			last.Data = last.Data[:len(last.Data)-1]
+			offset--
+			data = append([]byte{b}, data...)
+		}
+	}
+	p.Records = append(p.Records, Record{
+		Offset: uint32(offset),
+		Data:   append([]byte(nil), data...),
+	})
+}
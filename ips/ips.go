@@ -0,0 +1,234 @@
+// Package ips implements the IPS patch format including RLE records and the truncation extension.
+//
+// Patches are applied to ROM contents without any copier header.
+package ips
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/alttpo/snes"
+)
+
+var (
+	ErrInvalidHeader = errors.New("ips: invalid header")
+	ErrTruncated     = errors.New("ips: unexpected end of patch")
+	ErrTrailingData  = errors.New("ips: unexpected data after EOF marker")
+	ErrOutOfBounds   = errors.New("ips: record out of bounds")
+	ErrOverlap       = errors.New("ips: records overlap")
+)
+
+var (
+	header    = []byte("PATCH")
+	eofMarker = []byte("EOF")
+)
+
+// eofOffset is the record offset that would be confused with the EOF marker:
+const eofOffset = 0x454F46
+
+// maxSize is the largest file size addressable by a 24-bit record offset:
+const maxSize = 0x1000000
+
+// Record writes Data at Offset. RLE records repeat a single byte value for len(Data) bytes.
+type Record struct {
+	Offset uint32
+	Data   []byte
+	RLE    bool
+}
+
+func (r *Record) end() uint32 {
+	return r.Offset + uint32(len(r.Data))
+}
+
+type Patch struct {
+	Records []Record
+
+	// HasTruncate indicates the patch carries the truncation extension to resize the target to Truncate bytes:
+	HasTruncate bool
+	Truncate    uint32
+}
+
+// Parse decodes an IPS patch
+func Parse(b []byte) (p *Patch, err error) {
+	if !bytes.HasPrefix(b, header) {
+		return nil, ErrInvalidHeader
+	}
+	b = b[len(header):]
+
+	p = &Patch{}
+	for {
+		if len(b) < 3 {
+			return nil, ErrTruncated
+		}
+		if bytes.Equal(b[:3], eofMarker) {
+			b = b[3:]
+			break
+		}
+
+		offset := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		b = b[3:]
+		if len(b) < 2 {
+			return nil, ErrTruncated
+		}
+		size := int(b[0])<<8 | int(b[1])
+		b = b[2:]
+
+		if size == 0 {
+			// RLE record:
+			if len(b) < 3 {
+				return nil, ErrTruncated
+			}
+			count := int(b[0])<<8 | int(b[1])
+			if count == 0 {
+				return nil, fmt.Errorf("ips: empty RLE record at offset %06x", offset)
+			}
+			p.Records = append(p.Records, Record{
+				Offset: offset,
+				Data:   bytes.Repeat(b[2:3], count),
+				RLE:    true,
+			})
+			b = b[3:]
+			continue
+		}
+
+		if len(b) < size {
+			return nil, ErrTruncated
+		}
+		data := make([]byte, size)
+		copy(data, b[:size])
+		p.Records = append(p.Records, Record{Offset: offset, Data: data})
+		b = b[size:]
+	}
+
+	switch len(b) {
+	case 0:
+	case 3:
+		p.HasTruncate = true
+		p.Truncate = uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	default:
+		return nil, ErrTrailingData
+	}
+
+	return
+}
+
+// Validate checks that all records are within bounds and that no two records overlap
+func (p *Patch) Validate() error {
+	sorted := make([]*Record, len(p.Records))
+	for i := range p.Records {
+		r := &p.Records[i]
+		if len(r.Data) == 0 || len(r.Data) > 0xFFFF || r.end() > maxSize {
+			return fmt.Errorf("%w: offset %06x size %x", ErrOutOfBounds, r.Offset, len(r.Data))
+		}
+		sorted[i] = r
+	}
+	if p.HasTruncate && p.Truncate > maxSize {
+		return fmt.Errorf("%w: truncate to %x", ErrOutOfBounds, p.Truncate)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].end() > sorted[i].Offset {
+			return fmt.Errorf("%w: offset %06x and offset %06x", ErrOverlap, sorted[i-1].Offset, sorted[i].Offset)
+		}
+	}
+	return nil
+}
+
+// ApplyTo returns the patched contents; contents grows when a record writes past its end.
+// contents may be modified in place.
+func (p *Patch) ApplyTo(contents []byte) (out []byte, err error) {
+	if err = p.Validate(); err != nil {
+		return nil, err
+	}
+
+	out = contents
+	for i := range p.Records {
+		r := &p.Records[i]
+		if end := int(r.end()); end > len(out) {
+			out = grow(out, end)
+		}
+		copy(out[r.Offset:], r.Data)
+	}
+
+	if p.HasTruncate {
+		if int(p.Truncate) > len(out) {
+			out = grow(out, int(p.Truncate))
+		} else {
+			out = out[:p.Truncate]
+		}
+	}
+
+	return
+}
+
+// Apply patches the ROM contents and re-reads its header
+func (p *Patch) Apply(rom *snes.ROM) (err error) {
+	var out []byte
+	if out, err = p.ApplyTo(rom.Contents); err != nil {
+		return
+	}
+
+	rom.Contents = out
+	if uint64(rom.HeaderOffset)+0x50 <= uint64(len(rom.Contents)) {
+		err = rom.ReadHeader()
+	}
+	return
+}
+
+// grow extends b to size with zeroes, clearing any stale bytes in its spare capacity
+func grow(b []byte, size int) []byte {
+	if size <= cap(b) {
+		n := b[:size]
+		for i := len(b); i < size; i++ {
+			n[i] = 0
+		}
+		return n
+	}
+	n := make([]byte, size)
+	copy(n, b)
+	return n
+}
+
+// WriteTo encodes the patch
+func (p *Patch) WriteTo(w io.Writer) (n int64, err error) {
+	if err = p.Validate(); err != nil {
+		return
+	}
+
+	b := &bytes.Buffer{}
+	b.Write(header)
+	for i := range p.Records {
+		r := &p.Records[i]
+		if r.Offset == eofOffset {
+			return 0, fmt.Errorf("ips: record offset %06x is indistinguishable from EOF marker", r.Offset)
+		}
+
+		b.Write([]byte{byte(r.Offset >> 16), byte(r.Offset >> 8), byte(r.Offset)})
+		size := len(r.Data)
+		if r.RLE {
+			b.Write([]byte{0, 0, byte(size >> 8), byte(size), r.Data[0]})
+		} else {
+			b.Write([]byte{byte(size >> 8), byte(size)})
+			b.Write(r.Data)
+		}
+	}
+	b.Write(eofMarker)
+	if p.HasTruncate {
+		b.Write([]byte{byte(p.Truncate >> 16), byte(p.Truncate >> 8), byte(p.Truncate)})
+	}
+
+	return b.WriteTo(w)
+}
+
+// Bytes encodes the patch
+func (p *Patch) Bytes() ([]byte, error) {
+	b := &bytes.Buffer{}
+	if _, err := p.WriteTo(b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,203 @@
+package ips
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/alttpo/snes"
+)
+
+func TestParse(t *testing.T) {
+	patch := []byte("PATCH" +
+		"\x00\x00\x10" + "\x00\x02" + "\xAA\xBB" +
+		"\x00\x00\x20" + "\x00\x00" + "\x00\x04" + "\xCC" +
+		"EOF" +
+		"\x00\x00\x30")
+
+	p, err := Parse(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Records) != 2 {
+		t.Fatalf("len(Records) = %d, want 2", len(p.Records))
+	}
+	if r := p.Records[0]; r.Offset != 0x10 || r.RLE || !bytes.Equal(r.Data, []byte{0xAA, 0xBB}) {
+		t.Errorf("Records[0] = %#v", r)
+	}
+	if r := p.Records[1]; r.Offset != 0x20 || !r.RLE || !bytes.Equal(r.Data, []byte{0xCC, 0xCC, 0xCC, 0xCC}) {
+		t.Errorf("Records[1] = %#v", r)
+	}
+	if !p.HasTruncate || p.Truncate != 0x30 {
+		t.Errorf("Truncate = %v %x, want true 30", p.HasTruncate, p.Truncate)
+	}
+
+	out, err := p.ApplyTo(make([]byte, 0x40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, 0x30)
+	want[0x10], want[0x11] = 0xAA, 0xBB
+	copy(want[0x20:], []byte{0xCC, 0xCC, 0xCC, 0xCC})
+	if !bytes.Equal(out, want) {
+		t.Errorf("ApplyTo() = %x, want %x", out, want)
+	}
+
+	// encoding must round-trip exactly:
+	enc, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, patch) {
+		t.Errorf("Bytes() = %q, want %q", enc, patch)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr error
+	}{
+		{name: "bad header", patch: "PATCX" + "EOF", wantErr: ErrInvalidHeader},
+		{name: "no EOF", patch: "PATCH" + "\x00\x00\x10" + "\x00\x01" + "\xAA", wantErr: ErrTruncated},
+		{name: "short record", patch: "PATCH" + "\x00\x00\x10" + "\x00\x04" + "\xAA" + "EOF", wantErr: ErrTruncated},
+		{name: "trailing data", patch: "PATCH" + "EOF" + "\x00", wantErr: ErrTrailingData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.patch)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPatch_Validate(t *testing.T) {
+	p := &Patch{Records: []Record{
+		{Offset: 0x20, Data: []byte{1, 2, 3}},
+		{Offset: 0x10, Data: bytes.Repeat([]byte{0xFF}, 0x11), RLE: true},
+	}}
+	if _, err := p.ApplyTo(make([]byte, 0x40)); !errors.Is(err, ErrOverlap) {
+		t.Errorf("ApplyTo() error = %v, want %v", err, ErrOverlap)
+	}
+
+	p = &Patch{Records: []Record{
+		{Offset: 0xFFFFFF, Data: []byte{1, 2}},
+	}}
+	if _, err := p.ApplyTo(nil); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("ApplyTo() error = %v, want %v", err, ErrOutOfBounds)
+	}
+}
+
+func TestPatch_Apply_Grows(t *testing.T) {
+	contents := make([]byte, 0x10000)
+	rom, err := snes.NewROM("", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Patch{Records: []Record{
+		{Offset: 0x17FFF, Data: []byte{0x5A}},
+	}}
+	if err = p.Apply(rom); err != nil {
+		t.Fatal(err)
+	}
+	if len(rom.Contents) != 0x18000 {
+		t.Fatalf("len(Contents) = %x, want %x", len(rom.Contents), 0x18000)
+	}
+	if rom.Contents[0x17FFF] != 0x5A {
+		t.Fatal("expected patched byte past original end")
+	}
+}
+
+func TestPatch_ApplyTo_TruncateThenExtend(t *testing.T) {
+	truncate := &Patch{HasTruncate: true, Truncate: 2}
+	out, err := truncate.ApplyTo([]byte("ABCDEF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bytes cut off by the truncation remain in the spare capacity:
+	extend := &Patch{Records: []Record{{Offset: 5, Data: []byte{'Z'}}}}
+	if out, err = extend.ApplyTo(out); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{'A', 'B', 0, 0, 0, 'Z'}; !bytes.Equal(out, want) {
+		t.Errorf("ApplyTo() = %q, want %q", out, want)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	source := make([]byte, 0x480000)
+	rng.Read(source)
+
+	target := append([]byte(nil), source...)
+	// scattered single byte changes:
+	for i := 0; i < 100; i++ {
+		target[rng.Intn(len(target))] ^= 0xFF
+	}
+	// nearby changes worth bridging:
+	target[0x1000] ^= 1
+	target[0x1003] ^= 1
+	// a long fill:
+	for i := 0x2000; i < 0x3000; i++ {
+		target[i] = 0xEA
+	}
+	// a change at the offset that looks like the EOF marker:
+	target[eofOffset] ^= 0xFF
+	target[eofOffset+1] ^= 0xFF
+
+	p, err := Create(source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc) > 0x1000 {
+		t.Errorf("len(Bytes()) = %d, expected a small patch", len(enc))
+	}
+
+	p, err = Parse(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.ApplyTo(append([]byte(nil), source...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, target) {
+		t.Fatal("patched source differs from target")
+	}
+}
+
+func TestCreate_Resize(t *testing.T) {
+	source := make([]byte, 0x100)
+	for _, size := range []int{0x80, 0x180} {
+		target := make([]byte, size)
+		target[0x7F] = 1
+
+		p, err := Create(source, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := p.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err = Parse(enc); err != nil {
+			t.Fatal(err)
+		}
+		out, err := p.ApplyTo(append([]byte(nil), source...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, target) {
+			t.Errorf("size %x: patched source differs from target", size)
+		}
+	}
+}
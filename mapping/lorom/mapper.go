@@ -0,0 +1,69 @@
+package lorom
+
+import (
+	"github.com/alttpo/snes/mapping/util"
+)
+
+func BusAddressToROMOffset(busAddr uint32) (offset uint32, err error) {
+	return util.BusAddressToROMOffset(BusAddressToPak, busAddr)
+}
+
+func BusAddressToFileOffset(busAddr uint32) (f util.FileOffset, err error) {
+	return util.BusAddressToFileOffset(BusAddressToPak, busAddr)
+}
+
+func FileOffsetToBus(f util.FileOffset) (busAddr uint32, err error) {
+	return util.FileOffsetToBus(PakAddressToBus, f)
+}
+
+// Mirrors lists every bus address that maps to the pak address
+func Mirrors(pakAddr uint32) []uint32 {
+	return util.Mirrors(BusAddressToPak, pakAddr)
+}
+
+// MirrorRanges lists every bus segment that maps to part of the pak range
+func MirrorRanges(pakAddr, size uint32) []util.Segment {
+	return util.MirrorRanges(BusAddressToPak, pakAddr, size)
+}
+
+// SplitBusRange splits the bus range into segments that each map contiguously to pak space
+func SplitBusRange(busAddr, size uint32) []util.Segment {
+	return util.SplitBusRange(BusAddressToPak, busAddr, size)
+}
+
+var mapping = util.Mapping{
+	BusToPak: BusAddressToPak,
+	PakToBus: PakAddressToBus,
+}
+
+// Region classifies the bus address and finds the bounds of the region containing it
+func Region(busAddr uint32) util.Region {
+	return mapping.Region(busAddr)
+}
+
+// Mapper implements mapping.Mapper for LoROM
+type Mapper struct{}
+
+func (Mapper) Name() string { return "LoROM" }
+
+func (Mapper) BusAddressToPak(busAddr uint32) (uint32, error) { return BusAddressToPak(busAddr) }
+
+func (Mapper) PakAddressToBus(pakAddr uint32) (uint32, error) { return PakAddressToBus(pakAddr) }
+
+func (Mapper) BusAddressToROMOffset(busAddr uint32) (uint32, error) {
+	return BusAddressToROMOffset(busAddr)
+}
+
+func (Mapper) BusAddressToFileOffset(busAddr uint32) (util.FileOffset, error) {
+	return BusAddressToFileOffset(busAddr)
+}
+
+func (Mapper) FileOffsetToBus(f util.FileOffset) (uint32, error) { return FileOffsetToBus(f) }
+
+func (Mapper) Mirrors(pakAddr uint32) []uint32 { return Mirrors(pakAddr) }
+
+func (Mapper) MirrorRanges(pakAddr, size uint32) []util.Segment { return MirrorRanges(pakAddr, size) }
+
+func (Mapper) SplitBusRange(busAddr, size uint32) []util.Segment { return SplitBusRange(busAddr, size) }
+
+func (Mapper) Region(busAddr uint32) util.Region { return Region(busAddr) }
@@ -0,0 +1,114 @@
+// Package mapping provides a common Mapper interface over the cartridge mapping packages and a registry that selects
+// the Mapper for a ROM header's map mode.
+package mapping
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/alttpo/snes"
+	"github.com/alttpo/snes/mapping/exhirom"
+	"github.com/alttpo/snes/mapping/hirom"
+	"github.com/alttpo/snes/mapping/lorom"
+	"github.com/alttpo/snes/mapping/sa1rom"
+	"github.com/alttpo/snes/mapping/sdd1"
+	"github.com/alttpo/snes/mapping/spc7110"
+	"github.com/alttpo/snes/mapping/superfx"
+	"github.com/alttpo/snes/mapping/util"
+)
+
+// Mapper translates between SNES bus addresses, FX Pak Pro addresses and ROM file offsets for a cartridge layout
+type Mapper interface {
+	Name() string
+
+	BusAddressToPak(busAddr uint32) (pakAddr uint32, err error)
+	PakAddressToBus(pakAddr uint32) (busAddr uint32, err error)
+	BusAddressToROMOffset(busAddr uint32) (offset uint32, err error)
+
+	// BusAddressToFileOffset and FileOffsetToBus translate to and from ROM and SRAM file offsets
+	BusAddressToFileOffset(busAddr uint32) (f util.FileOffset, err error)
+	FileOffsetToBus(f util.FileOffset) (busAddr uint32, err error)
+
+	// Mirrors lists every bus address that maps to the pak address and MirrorRanges every bus segment that maps to
+	// part of the pak range; SplitBusRange splits a bus range into segments that map contiguously to pak space
+	Mirrors(pakAddr uint32) []uint32
+	MirrorRanges(pakAddr, size uint32) []util.Segment
+	SplitBusRange(busAddr, size uint32) []util.Segment
+
+	Region(busAddr uint32) util.Region
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[byte]Mapper{}
+	coprocessors = map[byte]Mapper{}
+)
+
+func init() {
+	Register(0x20, lorom.Mapper{})
+	Register(0x21, hirom.Mapper{})
+	Register(0x23, sa1rom.Mapper{})
+	Register(0x25, exhirom.Mapper{})
+	Register(0x2A, spc7110.Mapper{})
+
+	// SuperFX carts declare LoROM map mode:
+	RegisterCoprocessor(0x1, superfx.Mapper{})
+	// S-DD1 carts declare map mode $32:
+	RegisterCoprocessor(0x4, sdd1.Mapper{})
+}
+
+// mapModeKey ignores the FastROM bit
+func mapModeKey(mapMode byte) byte {
+	return mapMode &^ 0x10
+}
+
+// Register sets the Mapper used for a header map mode, replacing any existing one. The FastROM bit is ignored.
+func Register(mapMode byte, m Mapper) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[mapModeKey(mapMode)] = m
+}
+
+// RegisterCoprocessor sets the Mapper used for carts with the given coprocessor, replacing any existing one.
+// The coprocessor is the upper nibble of the header cartridge type and takes precedence over the map mode.
+func RegisterCoprocessor(coprocessor byte, m Mapper) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	coprocessors[coprocessor&0x0F] = m
+}
+
+// ForMapMode returns the Mapper registered for the header map mode
+func ForMapMode(mapMode byte) (m Mapper, err error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var ok bool
+	if m, ok = registry[mapModeKey(mapMode)]; !ok {
+		return nil, fmt.Errorf("mapping: no mapper registered for map mode $%02X", mapMode)
+	}
+	return
+}
+
+// ForHeader returns the Mapper registered for the header's coprocessor, if any, otherwise for its map mode
+func ForHeader(h *snes.Header) (Mapper, error) {
+	// cartridge types $x3..$xF indicate a coprocessor in the upper nibble:
+	if h.CartridgeType&0x0F >= 0x03 {
+		registryLock.RLock()
+		m, ok := coprocessors[h.CartridgeType>>4]
+		registryLock.RUnlock()
+		if ok {
+			return m, nil
+		}
+	}
+	return ForMapMode(h.MapMode)
+}
+
+// BusFile returns a snes.BusFile over the ROM contents that translates bus addresses through the Mapper for the ROM's
+// header
+func BusFile(rom *snes.ROM, busAddr uint32) (*snes.BusFile, error) {
+	m, err := ForHeader(&rom.Header)
+	if err != nil {
+		return nil, err
+	}
+	return rom.BusFile(m.BusAddressToPak, busAddr), nil
+}
@@ -0,0 +1,357 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes"
+	"github.com/alttpo/snes/mapping/util"
+)
+
+func TestForHeader(t *testing.T) {
+	tests := []struct {
+		name          string
+		mapMode       byte
+		cartridgeType byte
+		want          string
+		wantErr       bool
+	}{
+		{name: "LoROM", mapMode: 0x20, want: "LoROM"},
+		{name: "LoROM FastROM", mapMode: 0x30, want: "LoROM"},
+		{name: "HiROM", mapMode: 0x21, want: "HiROM"},
+		{name: "HiROM FastROM", mapMode: 0x31, want: "HiROM"},
+		{name: "SA-1", mapMode: 0x23, want: "SA-1"},
+		{name: "ExHiROM", mapMode: 0x35, want: "ExHiROM"},
+		{name: "LoROM+RAM+battery", mapMode: 0x20, cartridgeType: 0x02, want: "LoROM"},
+		{name: "SuperFX Star Fox", mapMode: 0x20, cartridgeType: 0x13, want: "SuperFX"},
+		{name: "SuperFX Yoshi's Island", mapMode: 0x20, cartridgeType: 0x15, want: "SuperFX"},
+		{name: "SuperFX2", mapMode: 0x20, cartridgeType: 0x1A, want: "SuperFX"},
+		{name: "S-DD1", mapMode: 0x32, cartridgeType: 0x45, want: "S-DD1"},
+		{name: "SPC7110", mapMode: 0x3A, cartridgeType: 0xF9, want: "SPC7110"},
+		{name: "unknown", mapMode: 0x2B, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ForHeader(&snes.Header{MapMode: tt.mapMode, CartridgeType: tt.cartridgeType})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ForHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := m.Name(); got != tt.want {
+				t.Errorf("ForHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusFile(t *testing.T) {
+	contents := make([]byte, 0x200000)
+	for i := range contents {
+		contents[i] = byte(i>>16) ^ byte(i>>8) ^ byte(i)
+	}
+	tests := []struct {
+		name    string
+		mapMode byte
+		busAddr uint32
+		offset  int
+		wantErr bool
+	}{
+		{name: "LoROM", mapMode: 0x20, busAddr: 0x01_8123, offset: 0x08123},
+		{name: "HiROM", mapMode: 0x21, busAddr: 0xC1_0123, offset: 0x10123},
+		{name: "SPC7110", mapMode: 0x3A, busAddr: 0xC1_0123, offset: 0x10123},
+		{name: "unknown", mapMode: 0x2B, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rom := &snes.ROM{Contents: contents, Header: snes.Header{MapMode: tt.mapMode}}
+			f, err := BusFile(rom, tt.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BusFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			p := []byte{0}
+			if _, err = f.Read(p); err != nil {
+				t.Fatal(err)
+			}
+			if p[0] != contents[tt.offset] {
+				t.Errorf("Read() = %02x, want %02x from offset %x", p[0], contents[tt.offset], tt.offset)
+			}
+		})
+	}
+}
+
+type testMapper struct{}
+
+func (testMapper) Name() string                                   { return "test" }
+func (testMapper) BusAddressToPak(busAddr uint32) (uint32, error) { return busAddr, nil }
+func (testMapper) PakAddressToBus(pakAddr uint32) (uint32, error) { return pakAddr, nil }
+func (testMapper) BusAddressToROMOffset(busAddr uint32) (uint32, error) {
+	return util.BusAddressToROMOffset(testMapper{}.BusAddressToPak, busAddr)
+}
+func (testMapper) BusAddressToFileOffset(busAddr uint32) (util.FileOffset, error) {
+	return util.BusAddressToFileOffset(testMapper{}.BusAddressToPak, busAddr)
+}
+func (testMapper) FileOffsetToBus(f util.FileOffset) (uint32, error) {
+	return util.FileOffsetToBus(testMapper{}.PakAddressToBus, f)
+}
+func (testMapper) Mirrors(pakAddr uint32) []uint32 {
+	return util.Mirrors(testMapper{}.BusAddressToPak, pakAddr)
+}
+func (testMapper) MirrorRanges(pakAddr, size uint32) []util.Segment {
+	return util.MirrorRanges(testMapper{}.BusAddressToPak, pakAddr, size)
+}
+func (testMapper) SplitBusRange(busAddr, size uint32) []util.Segment {
+	return util.SplitBusRange(testMapper{}.BusAddressToPak, busAddr, size)
+}
+func (testMapper) Region(busAddr uint32) util.Region {
+	return util.Region{Kind: util.RegionROM, Base: busAddr, Size: 1, Mirror: busAddr}
+}
+
+func TestRegister(t *testing.T) {
+	Register(0x2B, testMapper{})
+	defer func() {
+		registryLock.Lock()
+		delete(registry, 0x2B)
+		registryLock.Unlock()
+	}()
+
+	m, err := ForMapMode(0x3B)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name() != "test" {
+		t.Errorf("ForMapMode() = %v, want test", m.Name())
+	}
+}
+
+func TestMapper_BusAddressToROMOffset(t *testing.T) {
+	m, err := ForMapMode(0x20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offs, err := m.BusAddressToROMOffset(0x018000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offs != 0x8000 {
+		t.Errorf("BusAddressToROMOffset($018000) = $%06X, want $008000", offs)
+	}
+	if _, err = m.BusAddressToROMOffset(0x7E0000); err != util.ErrUnmappedAddress {
+		t.Errorf("BusAddressToROMOffset($7E0000) error = %v, want %v", err, util.ErrUnmappedAddress)
+	}
+}
+
+func TestMapper_FileOffset(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapMode byte
+		busAddr uint32
+		want    util.FileOffset
+		wantBus uint32
+		wantErr bool
+	}{
+		{
+			name:    "LoROM ROM",
+			mapMode: 0x20,
+			busAddr: 0x028000,
+			want:    util.FileOffset{File: util.FileROM, Offset: 0x010000},
+			wantBus: 0x828000,
+		},
+		{
+			name:    "LoROM SRAM",
+			mapMode: 0x20,
+			busAddr: 0x710000,
+			want:    util.FileOffset{File: util.FileSRAM, Offset: 0x008000},
+			wantBus: 0x710000,
+		},
+		{
+			name:    "HiROM ROM",
+			mapMode: 0x21,
+			busAddr: 0xC12345,
+			want:    util.FileOffset{File: util.FileROM, Offset: 0x012345},
+			wantBus: 0xC12345,
+		},
+		{
+			name:    "HiROM SRAM",
+			mapMode: 0x21,
+			busAddr: 0xA17000,
+			want:    util.FileOffset{File: util.FileSRAM, Offset: 0x003000},
+			wantBus: 0xA17000,
+		},
+		{
+			name:    "ExHiROM ROM",
+			mapMode: 0x25,
+			busAddr: 0x400000,
+			want:    util.FileOffset{File: util.FileROM, Offset: 0x400000},
+			wantBus: 0x400000,
+		},
+		{
+			name:    "WRAM has no file",
+			mapMode: 0x20,
+			busAddr: 0x7E0000,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ForMapMode(tt.mapMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.BusAddressToFileOffset(tt.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BusAddressToFileOffset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("BusAddressToFileOffset() = %v, want %v", got, tt.want)
+			}
+			busAddr, err := m.FileOffsetToBus(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if busAddr != tt.wantBus {
+				t.Errorf("FileOffsetToBus() = $%06X, want $%06X", busAddr, tt.wantBus)
+			}
+		})
+	}
+}
+
+func TestMapper_Mirrors(t *testing.T) {
+	m, err := ForMapMode(0x21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HiROM ROM offset $8000 is visible LoROM-style in banks $00-$3F and $80-$BF, and linearly in $40-$7D and $C0-$FF:
+	got := m.Mirrors(0x008000)
+	want := []uint32{0x018000, 0x408000, 0x818000, 0xC08000}
+	if len(got) != len(want) {
+		t.Fatalf("Mirrors() = %06x, want %06x", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Mirrors()[%d] = $%06X, want $%06X", i, got[i], want[i])
+		}
+	}
+
+	segs := m.SplitBusRange(0xC0FFF0, 0x20)
+	if len(segs) != 1 || segs[0] != (util.Segment{Bus: 0xC0FFF0, Pak: 0x00FFF0, Size: 0x20}) {
+		t.Errorf("SplitBusRange() = %+v, want one linear segment", segs)
+	}
+}
+
+func TestMapper_Region(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapMode byte
+		busAddr uint32
+		want    util.Region
+	}{
+		{
+			name:    "LoROM ROM",
+			mapMode: 0x20,
+			busAddr: 0x808123,
+			want:    util.Region{Kind: util.RegionROM, Base: 0x808000, Size: 0x8000, Pak: 0x000123, Mirror: 0x808123},
+		},
+		{
+			name:    "LoROM ROM mirror",
+			mapMode: 0x20,
+			busAddr: 0x008123,
+			want:    util.Region{Kind: util.RegionROM, Base: 0x008000, Size: 0x8000, Pak: 0x000123, Mirror: 0x808123, Mirrored: true},
+		},
+		{
+			name:    "LoROM SRAM",
+			mapMode: 0x20,
+			busAddr: 0x700010,
+			want:    util.Region{Kind: util.RegionSRAM, Base: 0x700000, Size: 0x8000, Pak: 0xE00010, Mirror: 0x700010},
+		},
+		{
+			name:    "LoROM WRAM",
+			mapMode: 0x20,
+			busAddr: 0x7E1234,
+			want:    util.Region{Kind: util.RegionWRAM, Base: 0x7E0000, Size: 0x20000, Pak: 0xF51234, Mirror: 0x7E1234},
+		},
+		{
+			name:    "LoROM low WRAM mirror",
+			mapMode: 0x20,
+			busAddr: 0x801234,
+			want:    util.Region{Kind: util.RegionWRAM, Base: 0x800000, Size: 0x2000, Pak: 0xF51234, Mirror: 0x7E1234, Mirrored: true},
+		},
+		{
+			name:    "LoROM PPU",
+			mapMode: 0x20,
+			busAddr: 0x002118,
+			want:    util.Region{Kind: util.RegionPPU, Base: 0x002100, Size: 0x40, Mirror: 0x2118},
+		},
+		{
+			name:    "LoROM APU mirror",
+			mapMode: 0x20,
+			busAddr: 0x812141,
+			want:    util.Region{Kind: util.RegionAPU, Base: 0x812140, Size: 0x40, Mirror: 0x2141, Mirrored: true},
+		},
+		{
+			name:    "LoROM CPU",
+			mapMode: 0x20,
+			busAddr: 0x004210,
+			want:    util.Region{Kind: util.RegionCPU, Base: 0x004200, Size: 0x20, Mirror: 0x4210},
+		},
+		{
+			name:    "LoROM DMA",
+			mapMode: 0x20,
+			busAddr: 0x004372,
+			want:    util.Region{Kind: util.RegionDMA, Base: 0x004300, Size: 0x80, Mirror: 0x4372},
+		},
+		{
+			name:    "LoROM open bus between registers",
+			mapMode: 0x20,
+			busAddr: 0x003000,
+			want:    util.Region{Kind: util.RegionOpenBus, Base: 0x002184, Size: 0x4016 - 0x2184, Mirror: 0x003000},
+		},
+		{
+			name:    "HiROM ROM",
+			mapMode: 0x21,
+			busAddr: 0xC12345,
+			want:    util.Region{Kind: util.RegionROM, Base: 0xC00000, Size: 0x400000, Pak: 0x012345, Mirror: 0xC12345},
+		},
+		{
+			name:    "HiROM SRAM",
+			mapMode: 0x21,
+			busAddr: 0x206000,
+			want:    util.Region{Kind: util.RegionSRAM, Base: 0x206000, Size: 0x2000, Pak: 0xE00000, Mirror: 0xA06000, Mirrored: true},
+		},
+		{
+			name:    "SA-1 registers",
+			mapMode: 0x23,
+			busAddr: 0x002230,
+			want:    util.Region{Kind: util.RegionExpansion, Base: 0x002200, Size: 0x200, Mirror: 0x2230},
+		},
+		{
+			name:    "SA-1 I-RAM",
+			mapMode: 0x23,
+			busAddr: 0x003100,
+			want:    util.Region{Kind: util.RegionExpansion, Base: 0x003000, Size: 0x800, Mirror: 0x3100},
+		},
+		{
+			name:    "SA-1 BW-RAM",
+			mapMode: 0x23,
+			busAddr: 0x412345,
+			want:    util.Region{Kind: util.RegionSRAM, Base: 0x400000, Size: 0x40000, Pak: 0xE12345, Mirror: 0x412345},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ForMapMode(tt.mapMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Region(tt.busAddr); got != tt.want {
+				t.Errorf("Region($%06X) = %#v, want %#v", tt.busAddr, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,11 @@
 // Package sa1rom
 //
-// This implementation has a caveat for SA-1 ROM access via SNES A-bus.
+// SA-1 ROM mapping on the SNES A-bus is dynamic. The super MMC bank registers $2220-$2223 (CXB, DXB, EXB, FXB)
+// each select a 1MiB ROM block, and BMAPS $2224 selects the BW-RAM block visible at $6000-$7FFF. State models
+// these registers; its methods translate addresses for the currently selected banks.
 //
-// SA-1 ROM mapping on SNES A-bus is dynamic, and we don't have a clean way to alter the mapping temporarily
-// and reset it back. Using the SNES A-bus is generally a bad idea to access dynamically mapped memory.
-//
-// A compromise is to just assume CX, DX, EX, FX are linearly mapped to banks $00..3F of linear ROM.
+// The package-level functions assume the power-on State, where CX, DX, EX, FX are linearly mapped to banks $00..3F
+// of linear ROM:
 //
 // CX : SNES A-bus banks $00..1F
 // DX : SNES A-bus banks $20..3F
@@ -20,22 +20,32 @@ import (
 // https://archive.org/details/SNESDevManual/book2/page/n15/mode/1up?view=theater
 
 func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	return powerOn.BusAddressToPak(busAddr)
+}
+
+func PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+	return powerOn.PakAddressToBus(pakAddr)
+}
+
+func (s *State) BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
 	bank := busAddr >> 16
 	offs := busAddr & 0xFFFF
 
-	if bank >= 0xC0 {
+	if bank >= 0x1_00 {
+		return 0, util.ErrUnmappedAddress
+	} else if bank >= 0xC0 {
 		// C0..FF
 		// ROM area CX, DX, EX, FX:
-		pakAddr = (bank-0xC0)<<16 | offs
+		pakAddr = s.romBlock(int(bank-0xC0)>>4, true) | (busAddr & 0x0FFFFF)
 		return
 	} else if bank >= 0x80 {
 		// 80..BF
 		if offs >= 0x8000 {
-			// ROM for CX, DX:
-			pakAddr = ((bank - 0x80 + 0x40) << 15) | (offs & 0x7FFF)
+			// ROM for EX, FX:
+			pakAddr = s.loROM(busAddr)
 		} else if offs >= 0x6000 {
-			// BW-RAM image dynamically selects a single $2000 sized block:
-			pakAddr = 0xE00000 | (offs - 0x6000)
+			// BW-RAM image selected by BMAPS:
+			pakAddr = s.bwramImage(offs)
 		} else if offs < 0x2000 {
 			// WRAM
 			pakAddr = 0xF50000 | offs
@@ -54,9 +64,8 @@ func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
 		err = util.ErrUnmappedAddress
 	} else if bank >= 0x44 {
 		// 44..4F
-		// BW-RAM image dynamically selects a single $2000 sized block:
-		abs := (busAddr - 0x440000) & 0x1FFF
-		pakAddr = 0xE00000 | abs
+		// BW-RAM image selected by BMAPS:
+		pakAddr = s.bwramImage(0x6000 + offs&0x1FFF)
 		return
 	} else if bank >= 0x40 {
 		// 40..43
@@ -67,10 +76,10 @@ func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
 		// 00..3F
 		if offs >= 0x8000 {
 			// ROM for CX, DX:
-			pakAddr = (bank << 15) | (offs & 0x7FFF)
+			pakAddr = s.loROM(busAddr)
 		} else if offs >= 0x6000 {
-			// BW-RAM image dynamically selects a single $2000 sized block:
-			pakAddr = 0xE00000 | (offs - 0x6000)
+			// BW-RAM image selected by BMAPS:
+			pakAddr = s.bwramImage(offs)
 		} else if offs < 0x2000 {
 			// WRAM
 			pakAddr = 0xF50000 | offs
@@ -83,7 +92,7 @@ func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
 	return 0, util.ErrUnmappedAddress
 }
 
-func PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+func (s *State) PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
 	if pakAddr >= 0xF50000 {
 		// WRAM is easy:
 		// mirror fxpakpro banks $F7..FF back down into WRAM $F5..F6 because these banks in FX Pak Pro space
@@ -102,22 +111,49 @@ func PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
 		return
 	} else if pakAddr < 0xE00000 {
 		// ROM access:
+		block := pakAddr >> 20
+		offs := pakAddr & 0x0FFFFF
 
-		// Mirror the top $40..DF fxpakpro banks down to $00..3F because the SNES A-bus can only map in $40 full banks
-		// of ROM:
-		abs := pakAddr & 0x3FFFFF
-		bank := abs >> 15
-		offs := abs & 0x7FFF
-
-		if bank >= 0x40 {
-			// EX and FX map to SNES A-bus banks $80 and $A0:
-			busAddr = (0x80+(bank-0x40))<<16 | (0x8000 + offs)
-		} else {
-			// CX and DX map to SNES A-bus banks $00 and $20:
-			busAddr = bank<<16 | (0x8000 + offs)
+		// prefer the $8000-$FFFF windows that CX, DX, EX, FX map in banks $00..3F and $80..BF:
+		for i := 0; i < 4; i++ {
+			if s.romBlock(i, false) == block<<20 {
+				busAddr = (loROMBanks[i]+offs>>15)<<16 | 0x8000 | (offs & 0x7FFF)
+				return
+			}
+		}
+		// fall back to the linear windows in banks $C0..FF:
+		for i := 0; i < 4; i++ {
+			if s.romBlock(i, true) == block<<20 {
+				busAddr = (0xC0+uint32(i)<<4)<<16 | offs
+				return
+			}
 		}
-
-		return
 	}
 	return 0, util.ErrUnmappedAddress
 }
+
+// SA1BusAddressToPak translates an address on the SA-1 CPU's bus. The SA-1 sees the same ROM and BW-RAM as the SNES
+// CPU, except that BMAP selects its BW-RAM image at $6000-$7FFF. WRAM is not accessible to the SA-1; I-RAM and the
+// bitmap view of BW-RAM at banks $60..6F are not part of FX Pak Pro space.
+func (s *State) SA1BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	bank := busAddr >> 16
+	offs := busAddr & 0xFFFF
+
+	if bank >= 0x1_00 {
+		return 0, util.ErrUnmappedAddress
+	} else if bank >= 0x60 && bank < 0x70 {
+		// bitmap view of BW-RAM:
+		return 0, util.ErrUnmappedAddress
+	} else if bank >= 0x7E && bank < 0x80 {
+		// no WRAM on the SA-1 bus:
+		return 0, util.ErrUnmappedAddress
+	} else if bank&0x40 == 0 && offs < 0x8000 {
+		if offs >= 0x6000 && s.BMAP&0x80 == 0 {
+			// BW-RAM image selected by BMAP:
+			return 0xE00000 | uint32(s.BMAP&0x1F)<<13 | (offs - 0x6000), nil
+		}
+		// I-RAM, registers or bitmap image:
+		return 0, util.ErrUnmappedAddress
+	}
+	return s.BusAddressToPak(busAddr)
+}
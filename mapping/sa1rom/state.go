@@ -0,0 +1,69 @@
+package sa1rom
+
+// State holds the SA-1 super MMC bank registers and BW-RAM block selection
+type State struct {
+	// CXB, DXB, EXB and FXB ($2220-$2223) select the 1MiB ROM block in bits 0-2. When bit 7 is set the block is
+	// also mapped into the $8000-$FFFF windows of banks $00..1F, $20..3F, $80..9F and $A0..BF respectively;
+	// otherwise those windows show blocks 0, 1, 2 and 3.
+	CXB, DXB, EXB, FXB uint8
+	// BMAPS ($2224) selects the 8KiB BW-RAM block visible to the SNES CPU at $6000-$7FFF in bits 0-4
+	BMAPS uint8
+	// BMAP ($2225) selects the 8KiB BW-RAM block visible to the SA-1 CPU at $6000-$7FFF in bits 0-6; bit 7
+	// selects the bitmap view instead
+	BMAP uint8
+}
+
+// PowerOnState returns the registers as set at power-on, mapping ROM linearly
+func PowerOnState() State {
+	return State{CXB: 0, DXB: 1, EXB: 2, FXB: 3}
+}
+
+var powerOn = PowerOnState()
+
+var loROMBanks = [4]uint32{0x00, 0x20, 0x80, 0xA0}
+
+func (s *State) bankRegisters() [4]uint8 {
+	return [4]uint8{s.CXB, s.DXB, s.EXB, s.FXB}
+}
+
+// romBlock returns the pak address of the ROM block mapped for CX (0), DX (1), EX (2) or FX (3)
+func (s *State) romBlock(i int, hiROM bool) uint32 {
+	xb := s.bankRegisters()[i]
+	if !hiROM && xb&0x80 == 0 {
+		return uint32(i) << 20
+	}
+	return uint32(xb&0x07) << 20
+}
+
+// loROM translates an address in the $8000-$FFFF window of banks $00..3F or $80..BF
+func (s *State) loROM(busAddr uint32) uint32 {
+	bank := busAddr >> 16
+	i := int(bank>>5&1 | bank>>6&2)
+	return s.romBlock(i, false) | (bank&0x1F)<<15 | (busAddr & 0x7FFF)
+}
+
+// bwramImage translates an address in the $6000-$7FFF BW-RAM image selected by BMAPS
+func (s *State) bwramImage(offs uint32) uint32 {
+	return 0xE00000 | uint32(s.BMAPS&0x1F)<<13 | (offs - 0x6000)
+}
+
+// WriteRegister updates the state for a write to $2220-$2225 and reports whether addr is one of them
+func (s *State) WriteRegister(addr uint16, value uint8) bool {
+	switch addr {
+	case 0x2220:
+		s.CXB = value & 0x87
+	case 0x2221:
+		s.DXB = value & 0x87
+	case 0x2222:
+		s.EXB = value & 0x87
+	case 0x2223:
+		s.FXB = value & 0x87
+	case 0x2224:
+		s.BMAPS = value & 0x1F
+	case 0x2225:
+		s.BMAP = value
+	default:
+		return false
+	}
+	return true
+}
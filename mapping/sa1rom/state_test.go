@@ -0,0 +1,216 @@
+package sa1rom
+
+import "testing"
+
+func TestState_BusAddressToPak(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   State
+		busAddr uint32
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name:    "CX window ignores CXB without bit 7",
+			state:   State{CXB: 0x04, DXB: 1, EXB: 2, FXB: 3},
+			busAddr: 0x008000,
+			want:    0x000000,
+		},
+		{
+			name:    "CX window follows CXB with bit 7",
+			state:   State{CXB: 0x84, DXB: 1, EXB: 2, FXB: 3},
+			busAddr: 0x018000,
+			want:    0x408000,
+		},
+		{
+			name:    "DX window follows DXB with bit 7",
+			state:   State{CXB: 0, DXB: 0x85, EXB: 2, FXB: 3},
+			busAddr: 0x3FFFFF,
+			want:    0x5FFFFF,
+		},
+		{
+			name:    "EX window follows EXB with bit 7",
+			state:   State{CXB: 0, DXB: 1, EXB: 0x86, FXB: 3},
+			busAddr: 0x808000,
+			want:    0x600000,
+		},
+		{
+			name:    "FX window follows FXB with bit 7",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 0x87},
+			busAddr: 0xA08000,
+			want:    0x700000,
+		},
+		{
+			name:    "bank $C0 follows CXB regardless of bit 7",
+			state:   State{CXB: 0x04, DXB: 1, EXB: 2, FXB: 3},
+			busAddr: 0xC12345,
+			want:    0x412345,
+		},
+		{
+			name:    "bank $F0 follows FXB",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 0x05},
+			busAddr: 0xF00000,
+			want:    0x500000,
+		},
+		{
+			name:    "BW-RAM image selects BMAPS block",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 3, BMAPS: 0x03},
+			busAddr: 0x006010,
+			want:    0xE06010,
+		},
+		{
+			name:    "BW-RAM image mirror selects BMAPS block",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 3, BMAPS: 0x1F},
+			busAddr: 0x807FFF,
+			want:    0xE3FFFF,
+		},
+		{
+			name:    "BW-RAM linear ignores BMAPS",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 3, BMAPS: 0x03},
+			busAddr: 0x400000,
+			want:    0xE00000,
+		},
+		{
+			name:    "I-RAM unmapped",
+			state:   PowerOnState(),
+			busAddr: 0x003000,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.state.BusAddressToPak(tt.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BusAddressToPak() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BusAddressToPak() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_PakAddressToBus(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   State
+		pakAddr uint32
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name:    "block 0 in CX window",
+			state:   PowerOnState(),
+			pakAddr: 0x012345,
+			want:    0x02A345,
+		},
+		{
+			name:    "block 4 not mapped at power-on",
+			state:   PowerOnState(),
+			pakAddr: 0x400000,
+			wantErr: true,
+		},
+		{
+			name:    "block 4 in EX window",
+			state:   State{CXB: 0, DXB: 1, EXB: 0x84, FXB: 3},
+			pakAddr: 0x408000,
+			want:    0x818000,
+		},
+		{
+			name:    "block 5 only in linear window",
+			state:   State{CXB: 0, DXB: 5, EXB: 2, FXB: 3},
+			pakAddr: 0x512345,
+			want:    0xD12345,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.state.PakAddressToBus(tt.pakAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PakAddressToBus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PakAddressToBus() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_SA1BusAddressToPak(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   State
+		busAddr uint32
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name:    "BW-RAM image selects BMAP block",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 3, BMAPS: 0x01, BMAP: 0x02},
+			busAddr: 0x006000,
+			want:    0xE04000,
+		},
+		{
+			name:    "bitmap image unmapped",
+			state:   State{CXB: 0, DXB: 1, EXB: 2, FXB: 3, BMAP: 0x82},
+			busAddr: 0x006000,
+			wantErr: true,
+		},
+		{
+			name:    "no WRAM",
+			state:   PowerOnState(),
+			busAddr: 0x7E0000,
+			wantErr: true,
+		},
+		{
+			name:    "no low WRAM",
+			state:   PowerOnState(),
+			busAddr: 0x000100,
+			wantErr: true,
+		},
+		{
+			name:    "bitmap BW-RAM banks unmapped",
+			state:   PowerOnState(),
+			busAddr: 0x600000,
+			wantErr: true,
+		},
+		{
+			name:    "ROM same as SNES CPU",
+			state:   State{CXB: 0x86, DXB: 1, EXB: 2, FXB: 3},
+			busAddr: 0x008000,
+			want:    0x600000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.state.SA1BusAddressToPak(tt.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SA1BusAddressToPak() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SA1BusAddressToPak() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_WriteRegister(t *testing.T) {
+	s := PowerOnState()
+	if s.WriteRegister(0x2226, 0xFF) {
+		t.Error("WriteRegister($2226) = true, want false")
+	}
+	if !s.WriteRegister(0x2220, 0xFC) {
+		t.Fatal("WriteRegister($2220) = false, want true")
+	}
+	if s.CXB != 0x84 {
+		t.Errorf("CXB = $%02x, want $84", s.CXB)
+	}
+	m := Mapper{State: &s}
+	if got, _ := m.BusAddressToPak(0x008000); got != 0x400000 {
+		t.Errorf("BusAddressToPak($008000) = 0x%06x, want 0x400000", got)
+	}
+	// the package-level functions must not see the switch:
+	if got, _ := BusAddressToPak(0x008000); got != 0x000000 {
+		t.Errorf("BusAddressToPak($008000) = 0x%06x, want 0x000000", got)
+	}
+}
@@ -0,0 +1,120 @@
+// Package sdd1 maps S-DD1 cartridges such as Star Ocean and Street Fighter Alpha 2.
+//
+// ROM      : SNES A-bus banks $00..3F,$80..BF:8000-FFFF map the first 2MiB LoROM-style
+// ROM      : SNES A-bus banks $C0..FF:0000-FFFF are four 1MiB windows selected by registers $4804-$4807
+// SRAM     : SNES A-bus banks $70..73:0000-7FFF
+// Registers: $4800-$4807
+//
+// The S-DD1 bank registers live at $4804-$4807; they are not the SA-1 registers at $2220-$2223 which serve the
+// same purpose on SA-1 carts. Translations reflect the banks selected in a State. The package-level functions assume
+// the power-on State.
+package sdd1
+
+import (
+	"github.com/alttpo/snes/mapping/util"
+)
+
+// State holds the S-DD1 ROM bank registers $4804-$4807
+type State struct {
+	// Banks selects the 1MiB ROM block mapped at banks $C0-$CF, $D0-$DF, $E0-$EF and $F0-$FF
+	Banks [4]uint8
+}
+
+// PowerOnState returns the bank registers as set at power-on, mapping ROM linearly at $C0-$FF
+func PowerOnState() State {
+	return State{Banks: [4]uint8{0, 1, 2, 3}}
+}
+
+var powerOn = PowerOnState()
+
+// WriteRegister updates the state for a write to a bank register and reports whether addr is one
+func (s *State) WriteRegister(addr uint16, value uint8) bool {
+	if addr < 0x4804 || addr > 0x4807 {
+		return false
+	}
+	s.Banks[addr-0x4804] = value & 0x07
+	return true
+}
+
+// ReadRegister reads back a bank register and reports whether addr is one
+func (s *State) ReadRegister(addr uint16) (value uint8, ok bool) {
+	if addr < 0x4804 || addr > 0x4807 {
+		return 0, false
+	}
+	return s.Banks[addr-0x4804], true
+}
+
+func (s *State) BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	if busAddr >= 0x1_000000 {
+		return 0, util.ErrUnmappedAddress
+	}
+
+	bank := busAddr >> 16
+	offs := busAddr & 0xFFFF
+	if bank >= 0xC0 {
+		// ROM access through the bank windows: $C0:0000-$FF:FFFF
+		window := (bank - 0xC0) >> 4
+		rom := uint32(s.Banks[window])<<20 | (busAddr & 0x0FFFFF)
+		return rom, nil
+	} else if bank >= 0x7E && bank < 0x80 {
+		// WRAM access:                          $7E:0000-$7F:FFFF
+		wram := (busAddr - 0x7E0000) + 0xF50000
+		return wram, nil
+	} else if bank >= 0x70 && bank < 0x74 {
+		if offs < 0x8000 {
+			// SRAM access:                      $70:0000-$73:7FFF
+			sram := util.BankToLinear(busAddr-0x700000) + 0xE00000
+			return sram, nil
+		}
+	} else if bank&0x7F < 0x40 {
+		if offs&0x8000 != 0 {
+			// ROM access:                       $00:8000-$3F:FFFF
+			rom := util.BankToLinear(busAddr&0x3F7FFF) + 0x000000
+			return rom, nil
+		} else if offs < 0x2000 {
+			// Lower 8KiB of WRAM:               $00:0000-$3F:1FFF
+			wram := offs + 0xF50000
+			return wram, nil
+		}
+	}
+	return 0, util.ErrUnmappedAddress
+}
+
+func (s *State) PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+	if pakAddr >= 0xF50000 && pakAddr < 0x1_000000 {
+		// mirror bank $F7..FF back down into WRAM because these banks in FX Pak Pro space
+		// are not available on the SNES bus; they are copies of otherwise inaccessible memory
+		// like VRAM, CGRAM, OAM, etc.:
+		busAddr = ((pakAddr - 0xF50000) & 0x01FFFF) + 0x7E0000
+		return
+	} else if pakAddr >= 0xE00000 && pakAddr < 0xF00000 {
+		// SRAM is limited to 4 half banks; mirror the rest down:
+		abs := (pakAddr - 0xE00000) & 0x01FFFF
+		busAddr = (0x70+(abs>>15))<<16 | (abs & 0x7FFF)
+		return
+	} else if pakAddr < 0xE00000 {
+		// ROM access:
+		if pakAddr < 0x200000 {
+			// first 2MiB is always mapped at banks $80-$BF:
+			busAddr = (0x80+(pakAddr>>15))<<16 | (pakAddr & 0x7FFF) | 0x8000
+			return
+		}
+		// otherwise ROM is only visible through a bank window that selects its 1MiB block:
+		block := uint8(pakAddr >> 20)
+		for i, b := range s.Banks {
+			if b == block {
+				busAddr = (0xC0+uint32(i)<<4)<<16 | (pakAddr & 0x0FFFFF)
+				return
+			}
+		}
+	}
+	return 0, util.ErrUnmappedAddress
+}
+
+func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	return powerOn.BusAddressToPak(busAddr)
+}
+
+func PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+	return powerOn.PakAddressToBus(pakAddr)
+}
@@ -0,0 +1,187 @@
+package sdd1
+
+import "testing"
+
+func TestState_BusAddressToPak(t *testing.T) {
+	type args struct {
+		banks   [4]uint8
+		busAddr uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name: "ROM bank $00:8000",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x008000},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $3F:FFFF",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x3FFFFF},
+			want: 0x1FFFFF,
+		},
+		{
+			name: "ROM bank $80:8000 ignores banks",
+			args: args{banks: [4]uint8{7, 6, 5, 4}, busAddr: 0x808000},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $C0:0000 power-on",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0xC00000},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $FF:FFFF power-on",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0xFFFFFF},
+			want: 0x3FFFFF,
+		},
+		{
+			name: "ROM bank $C0:0000 switched",
+			args: args{banks: [4]uint8{4, 1, 2, 3}, busAddr: 0xC00000},
+			want: 0x400000,
+		},
+		{
+			name: "ROM bank $D5:1234 switched",
+			args: args{banks: [4]uint8{0, 7, 2, 3}, busAddr: 0xD51234},
+			want: 0x751234,
+		},
+		{
+			name: "ROM bank $F0:0000 switched",
+			args: args{banks: [4]uint8{0, 1, 2, 5}, busAddr: 0xF00000},
+			want: 0x500000,
+		},
+		{
+			name: "SRAM bank $70:0000",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x700000},
+			want: 0xE00000,
+		},
+		{
+			name: "SRAM bank $71:7FFF",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x717FFF},
+			want: 0xE0FFFF,
+		},
+		{
+			name: "WRAM bank $00:1FFF",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x001FFF},
+			want: 0xF51FFF,
+		},
+		{
+			name: "WRAM bank $7F:FFFF",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x7FFFFF},
+			want: 0xF6FFFF,
+		},
+		{
+			name:    "registers bank $00:4804",
+			args:    args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x004804},
+			wantErr: true,
+		},
+		{
+			name:    "unmapped bank $40:0000",
+			args:    args{banks: [4]uint8{0, 1, 2, 3}, busAddr: 0x400000},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &State{Banks: tt.args.banks}
+			got, err := s.BusAddressToPak(tt.args.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BusAddressToPak() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BusAddressToPak() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_PakAddressToBus(t *testing.T) {
+	type args struct {
+		banks   [4]uint8
+		pakAddr uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name: "ROM first 2MiB",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0x000000},
+			want: 0x808000,
+		},
+		{
+			name: "ROM last byte of 2MiB",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0x1FFFFF},
+			want: 0xBFFFFF,
+		},
+		{
+			name: "ROM block 3 power-on",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0x312345},
+			want: 0xF12345,
+		},
+		{
+			name: "ROM block 6 switched",
+			args: args{banks: [4]uint8{0, 6, 2, 3}, pakAddr: 0x6ABCDE},
+			want: 0xDABCDE,
+		},
+		{
+			name:    "ROM block 6 not mapped",
+			args:    args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0x600000},
+			wantErr: true,
+		},
+		{
+			name: "SRAM first byte",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0xE00000},
+			want: 0x700000,
+		},
+		{
+			name: "SRAM second bank",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0xE08000},
+			want: 0x710000,
+		},
+		{
+			name: "WRAM",
+			args: args{banks: [4]uint8{0, 1, 2, 3}, pakAddr: 0xF51234},
+			want: 0x7E1234,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &State{Banks: tt.args.banks}
+			got, err := s.PakAddressToBus(tt.args.pakAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PakAddressToBus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PakAddressToBus() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_WriteRegister(t *testing.T) {
+	s := PowerOnState()
+	if s.WriteRegister(0x4803, 0x05) {
+		t.Error("WriteRegister($4803) = true, want false")
+	}
+	if !s.WriteRegister(0x4805, 0xFD) {
+		t.Fatal("WriteRegister($4805) = false, want true")
+	}
+	if v, ok := s.ReadRegister(0x4805); !ok || v != 0x05 {
+		t.Errorf("ReadRegister($4805) = $%02x, %v, want $05, true", v, ok)
+	}
+
+	m := Mapper{State: &s}
+	if got, _ := m.BusAddressToPak(0xD00000); got != 0x500000 {
+		t.Errorf("BusAddressToPak($D00000) = 0x%06x, want 0x500000", got)
+	}
+	// the package-level functions must not see the switch:
+	if got, _ := BusAddressToPak(0xD00000); got != 0x100000 {
+		t.Errorf("BusAddressToPak($D00000) = 0x%06x, want 0x100000", got)
+	}
+}
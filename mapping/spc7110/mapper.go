@@ -0,0 +1,124 @@
+package spc7110
+
+import (
+	"github.com/alttpo/snes/mapping/util"
+)
+
+var registers = []util.IORange{
+	// SPC7110 decompression, data port, ALU, bank and RTC registers:
+	{Kind: util.RegionExpansion, Start: 0x4800, End: 0x4843},
+}
+
+func (s *State) BusAddressToROMOffset(busAddr uint32) (offset uint32, err error) {
+	return util.BusAddressToROMOffset(s.BusAddressToPak, busAddr)
+}
+
+func (s *State) BusAddressToFileOffset(busAddr uint32) (f util.FileOffset, err error) {
+	return util.BusAddressToFileOffset(s.BusAddressToPak, busAddr)
+}
+
+func (s *State) FileOffsetToBus(f util.FileOffset) (busAddr uint32, err error) {
+	return util.FileOffsetToBus(s.PakAddressToBus, f)
+}
+
+// Mirrors lists every bus address that maps to the pak address
+func (s *State) Mirrors(pakAddr uint32) []uint32 {
+	return util.Mirrors(s.BusAddressToPak, pakAddr)
+}
+
+// MirrorRanges lists every bus segment that maps to part of the pak range
+func (s *State) MirrorRanges(pakAddr, size uint32) []util.Segment {
+	return util.MirrorRanges(s.BusAddressToPak, pakAddr, size)
+}
+
+// SplitBusRange splits the bus range into segments that each map contiguously to pak space
+func (s *State) SplitBusRange(busAddr, size uint32) []util.Segment {
+	return util.SplitBusRange(s.BusAddressToPak, busAddr, size)
+}
+
+// Region classifies the bus address and finds the bounds of the region containing it
+func (s *State) Region(busAddr uint32) util.Region {
+	return util.Mapping{
+		BusToPak:  s.BusAddressToPak,
+		PakToBus:  s.PakAddressToBus,
+		Expansion: registers,
+	}.Region(busAddr)
+}
+
+func BusAddressToROMOffset(busAddr uint32) (offset uint32, err error) {
+	return powerOn.BusAddressToROMOffset(busAddr)
+}
+
+func BusAddressToFileOffset(busAddr uint32) (f util.FileOffset, err error) {
+	return powerOn.BusAddressToFileOffset(busAddr)
+}
+
+func FileOffsetToBus(f util.FileOffset) (busAddr uint32, err error) {
+	return powerOn.FileOffsetToBus(f)
+}
+
+// Mirrors lists every bus address that maps to the pak address
+func Mirrors(pakAddr uint32) []uint32 {
+	return powerOn.Mirrors(pakAddr)
+}
+
+// MirrorRanges lists every bus segment that maps to part of the pak range
+func MirrorRanges(pakAddr, size uint32) []util.Segment {
+	return powerOn.MirrorRanges(pakAddr, size)
+}
+
+// SplitBusRange splits the bus range into segments that each map contiguously to pak space
+func SplitBusRange(busAddr, size uint32) []util.Segment {
+	return powerOn.SplitBusRange(busAddr, size)
+}
+
+// Region classifies the bus address and finds the bounds of the region containing it
+func Region(busAddr uint32) util.Region {
+	return powerOn.Region(busAddr)
+}
+
+// Mapper implements mapping.Mapper for SPC7110; a nil State assumes the power-on bank registers
+type Mapper struct {
+	State *State
+}
+
+func (m Mapper) state() *State {
+	if m.State == nil {
+		return &powerOn
+	}
+	return m.State
+}
+
+func (Mapper) Name() string { return "SPC7110" }
+
+func (m Mapper) BusAddressToPak(busAddr uint32) (uint32, error) {
+	return m.state().BusAddressToPak(busAddr)
+}
+
+func (m Mapper) PakAddressToBus(pakAddr uint32) (uint32, error) {
+	return m.state().PakAddressToBus(pakAddr)
+}
+
+func (m Mapper) BusAddressToROMOffset(busAddr uint32) (uint32, error) {
+	return m.state().BusAddressToROMOffset(busAddr)
+}
+
+func (m Mapper) BusAddressToFileOffset(busAddr uint32) (util.FileOffset, error) {
+	return m.state().BusAddressToFileOffset(busAddr)
+}
+
+func (m Mapper) FileOffsetToBus(f util.FileOffset) (uint32, error) {
+	return m.state().FileOffsetToBus(f)
+}
+
+func (m Mapper) Mirrors(pakAddr uint32) []uint32 { return m.state().Mirrors(pakAddr) }
+
+func (m Mapper) MirrorRanges(pakAddr, size uint32) []util.Segment {
+	return m.state().MirrorRanges(pakAddr, size)
+}
+
+func (m Mapper) SplitBusRange(busAddr, size uint32) []util.Segment {
+	return m.state().SplitBusRange(busAddr, size)
+}
+
+func (m Mapper) Region(busAddr uint32) util.Region { return m.state().Region(busAddr) }
@@ -0,0 +1,127 @@
+// Package spc7110 maps SPC7110 cartridges such as Far East of Eden Zero and Momotarou Dentetsu Happy.
+//
+// The ROM file holds 1MiB of program ROM followed by data ROM.
+//
+// Program ROM: SNES A-bus banks $00..3F,$80..BF:8000-FFFF (LoROM-style) and $C0..CF:0000-FFFF (linear)
+// Data ROM   : SNES A-bus banks $D0..DF, $E0..EF, $F0..FF:0000-FFFF are 1MiB windows selected by registers $4831-$4833
+// SRAM       : SNES A-bus banks $00..3F,$80..BF:6000-7FFF
+// Registers  : $4800-$4842
+//
+// Translations reflect the banks selected in a State. The package-level functions assume the power-on State.
+package spc7110
+
+import (
+	"github.com/alttpo/snes/mapping/util"
+)
+
+const (
+	// ProgramROMSize is the size of program ROM at the start of the ROM file
+	ProgramROMSize = 0x100000
+)
+
+// State holds the SPC7110 data ROM bank registers $4831-$4833
+type State struct {
+	// Banks selects the 1MiB data ROM block mapped at banks $D0-$DF, $E0-$EF and $F0-$FF
+	Banks [3]uint8
+}
+
+// PowerOnState returns the bank registers as set at power-on, mapping data ROM linearly at $D0-$FF
+func PowerOnState() State {
+	return State{Banks: [3]uint8{0, 1, 2}}
+}
+
+var powerOn = PowerOnState()
+
+// WriteRegister updates the state for a write to a bank register and reports whether addr is one
+func (s *State) WriteRegister(addr uint16, value uint8) bool {
+	if addr < 0x4831 || addr > 0x4833 {
+		return false
+	}
+	s.Banks[addr-0x4831] = value & 0x07
+	return true
+}
+
+// ReadRegister reads back a bank register and reports whether addr is one
+func (s *State) ReadRegister(addr uint16) (value uint8, ok bool) {
+	if addr < 0x4831 || addr > 0x4833 {
+		return 0, false
+	}
+	return s.Banks[addr-0x4831], true
+}
+
+func (s *State) BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	if busAddr >= 0x1_000000 {
+		return 0, util.ErrUnmappedAddress
+	}
+
+	bank := busAddr >> 16
+	offs := busAddr & 0xFFFF
+	if bank >= 0xD0 {
+		// data ROM access through the bank windows: $D0:0000-$FF:FFFF
+		window := (bank - 0xD0) >> 4
+		rom := ProgramROMSize + (uint32(s.Banks[window])<<20 | (busAddr & 0x0FFFFF))
+		return rom, nil
+	} else if bank >= 0xC0 {
+		// program ROM access:                      $C0:0000-$CF:FFFF
+		rom := busAddr & 0x0FFFFF
+		return rom, nil
+	} else if bank >= 0x7E && bank < 0x80 {
+		// WRAM access:                             $7E:0000-$7F:FFFF
+		wram := (busAddr - 0x7E0000) + 0xF50000
+		return wram, nil
+	} else if bank&0x7F < 0x40 {
+		if offs&0x8000 != 0 {
+			// program ROM access:                  $00:8000-$3F:FFFF
+			rom := util.BankToLinear(busAddr&0x1F7FFF) + 0x000000
+			return rom, nil
+		} else if offs >= 0x6000 {
+			// SRAM access:                         $00:6000-$3F:7FFF
+			sram := (offs - 0x6000) + 0xE00000
+			return sram, nil
+		} else if offs < 0x2000 {
+			// Lower 8KiB of WRAM:                  $00:0000-$3F:1FFF
+			wram := offs + 0xF50000
+			return wram, nil
+		}
+	}
+	// banks $40-$4F decompression buffer and $50 decompression port are not memory
+	return 0, util.ErrUnmappedAddress
+}
+
+func (s *State) PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+	if pakAddr >= 0xF50000 && pakAddr < 0x1_000000 {
+		// mirror bank $F7..FF back down into WRAM because these banks in FX Pak Pro space
+		// are not available on the SNES bus; they are copies of otherwise inaccessible memory
+		// like VRAM, CGRAM, OAM, etc.:
+		busAddr = ((pakAddr - 0xF50000) & 0x01FFFF) + 0x7E0000
+		return
+	} else if pakAddr >= 0xE00000 && pakAddr < 0xF00000 {
+		// SRAM is limited to 8KiB; mirror the rest down:
+		busAddr = 0x006000 | (pakAddr & 0x1FFF)
+		return
+	} else if pakAddr < 0xE00000 {
+		if pakAddr < ProgramROMSize {
+			// program ROM is linear at banks $C0-$CF:
+			busAddr = 0xC00000 | pakAddr
+			return
+		}
+		// data ROM is only visible through a bank window that selects its 1MiB block:
+		abs := pakAddr - ProgramROMSize
+		block := uint8(abs >> 20)
+		for i, b := range s.Banks {
+			if b == block {
+				busAddr = (0xD0+uint32(i)<<4)<<16 | (abs & 0x0FFFFF)
+				return
+			}
+		}
+	}
+	return 0, util.ErrUnmappedAddress
+}
+
+func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	return powerOn.BusAddressToPak(busAddr)
+}
+
+func PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+	return powerOn.PakAddressToBus(pakAddr)
+}
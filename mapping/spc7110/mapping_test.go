@@ -0,0 +1,180 @@
+package spc7110
+
+import (
+	"testing"
+
+	"github.com/alttpo/snes/mapping/util"
+)
+
+func TestState_BusAddressToPak(t *testing.T) {
+	type args struct {
+		banks   [3]uint8
+		busAddr uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name: "program ROM bank $00:8000",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0x008000},
+			want: 0x000000,
+		},
+		{
+			name: "program ROM bank $1F:FFFF",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0x1FFFFF},
+			want: 0x0FFFFF,
+		},
+		{
+			name: "program ROM bank $20:8000 mirror",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0x208000},
+			want: 0x000000,
+		},
+		{
+			name: "program ROM bank $C0:0000",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0xC00000},
+			want: 0x000000,
+		},
+		{
+			name: "program ROM bank $CF:FFFF",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0xCFFFFF},
+			want: 0x0FFFFF,
+		},
+		{
+			name: "data ROM bank $D0:0000 power-on",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0xD00000},
+			want: 0x100000,
+		},
+		{
+			name: "data ROM bank $FF:FFFF power-on",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0xFFFFFF},
+			want: 0x3FFFFF,
+		},
+		{
+			name: "data ROM bank $E1:2345 switched",
+			args: args{banks: [3]uint8{0, 4, 2}, busAddr: 0xE12345},
+			want: 0x512345,
+		},
+		{
+			name: "SRAM bank $00:6000",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0x006000},
+			want: 0xE00000,
+		},
+		{
+			name: "SRAM bank $BF:7FFF",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0xBF7FFF},
+			want: 0xE01FFF,
+		},
+		{
+			name: "WRAM bank $7E:0000",
+			args: args{banks: [3]uint8{0, 1, 2}, busAddr: 0x7E0000},
+			want: 0xF50000,
+		},
+		{
+			name:    "decompression buffer bank $40:0000",
+			args:    args{banks: [3]uint8{0, 1, 2}, busAddr: 0x400000},
+			wantErr: true,
+		},
+		{
+			name:    "registers bank $00:4831",
+			args:    args{banks: [3]uint8{0, 1, 2}, busAddr: 0x004831},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &State{Banks: tt.args.banks}
+			got, err := s.BusAddressToPak(tt.args.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BusAddressToPak() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BusAddressToPak() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_PakAddressToBus(t *testing.T) {
+	type args struct {
+		banks   [3]uint8
+		pakAddr uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name: "program ROM",
+			args: args{banks: [3]uint8{0, 1, 2}, pakAddr: 0x012345},
+			want: 0xC12345,
+		},
+		{
+			name: "data ROM block 0 power-on",
+			args: args{banks: [3]uint8{0, 1, 2}, pakAddr: 0x100000},
+			want: 0xD00000,
+		},
+		{
+			name: "data ROM block 2 power-on",
+			args: args{banks: [3]uint8{0, 1, 2}, pakAddr: 0x3FFFFF},
+			want: 0xFFFFFF,
+		},
+		{
+			name: "data ROM block 4 switched",
+			args: args{banks: [3]uint8{0, 1, 4}, pakAddr: 0x512345},
+			want: 0xF12345,
+		},
+		{
+			name:    "data ROM block 4 not mapped",
+			args:    args{banks: [3]uint8{0, 1, 2}, pakAddr: 0x500000},
+			wantErr: true,
+		},
+		{
+			name: "SRAM",
+			args: args{banks: [3]uint8{0, 1, 2}, pakAddr: 0xE01234},
+			want: 0x007234,
+		},
+		{
+			name: "WRAM",
+			args: args{banks: [3]uint8{0, 1, 2}, pakAddr: 0xF61234},
+			want: 0x7F1234,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &State{Banks: tt.args.banks}
+			got, err := s.PakAddressToBus(tt.args.pakAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PakAddressToBus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PakAddressToBus() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestState_WriteRegister(t *testing.T) {
+	s := PowerOnState()
+	if s.WriteRegister(0x4830, 0x80) {
+		t.Error("WriteRegister($4830) = true, want false")
+	}
+	if !s.WriteRegister(0x4833, 0x03) {
+		t.Fatal("WriteRegister($4833) = false, want true")
+	}
+	if v, ok := s.ReadRegister(0x4833); !ok || v != 0x03 {
+		t.Errorf("ReadRegister($4833) = $%02x, %v, want $03, true", v, ok)
+	}
+
+	m := Mapper{State: &s}
+	if got, _ := m.BusAddressToPak(0xF00000); got != 0x400000 {
+		t.Errorf("BusAddressToPak($F00000) = 0x%06x, want 0x400000", got)
+	}
+	if got := m.Region(0x004833); got.Kind != util.RegionExpansion {
+		t.Errorf("Region($004833) = %v, want expansion", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Package superfx maps SuperFX (GSU) cartridges such as Star Fox and Yoshi's Island.
+//
+// ROM       : SNES A-bus banks $00..3F:8000-FFFF (LoROM-style) and $40..5F:0000-FFFF (linear)
+// Game RAM  : SNES A-bus banks $70..71:0000-FFFF; first 8KiB also at $00..3F:6000-7FFF
+// Backup RAM: SNES A-bus banks $78..79:0000-FFFF
+//
+// Banks $80..BF, $C0..DF and $F0..F1 mirror banks $00..3F, $40..5F and $70..71 respectively.
+//
+// In FX Pak Pro space, game RAM occupies $E00000-$E1FFFF and backup RAM $E20000-$E3FFFF.
+package superfx
+
+import (
+	"github.com/alttpo/snes/mapping/util"
+)
+
+func BusAddressToPak(busAddr uint32) (pakAddr uint32, err error) {
+	if busAddr >= 0x1_000000 {
+		return 0, util.ErrUnmappedAddress
+	}
+
+	bank := busAddr >> 16
+	offs := busAddr & 0xFFFF
+	if bank >= 0x7E && bank < 0x80 {
+		// WRAM access:            $7E:0000-$7F:FFFF
+		wram := (busAddr - 0x7E0000) + 0xF50000
+		return wram, nil
+	}
+
+	// banks $80-$FF mirror $00-$7F:
+	bank &= 0x7F
+	if bank < 0x40 {
+		if offs&0x8000 != 0 {
+			// ROM access:         $00:8000-$3F:FFFF
+			rom := util.BankToLinear(busAddr&0x3F7FFF) + 0x000000
+			return rom, nil
+		} else if offs >= 0x6000 {
+			// first 8KiB of game RAM: $00:6000-$3F:7FFF
+			ram := (offs - 0x6000) + 0xE00000
+			return ram, nil
+		} else if offs < 0x2000 {
+			// Lower 8KiB of WRAM: $00:0000-$3F:1FFF
+			wram := offs + 0xF50000
+			return wram, nil
+		}
+	} else if bank < 0x60 {
+		// ROM access:             $40:0000-$5F:FFFF
+		rom := (bank-0x40)<<16 | offs
+		return rom, nil
+	} else if bank >= 0x70 && bank < 0x72 {
+		// game RAM access:        $70:0000-$71:FFFF
+		ram := ((bank-0x70)<<16 | offs) + 0xE00000
+		return ram, nil
+	} else if bank >= 0x78 && bank < 0x7A && busAddr < 0x800000 {
+		// backup RAM access:      $78:0000-$79:FFFF
+		bram := ((bank-0x78)<<16 | offs) + 0xE20000
+		return bram, nil
+	}
+	return 0, util.ErrUnmappedAddress
+}
+
+func PakAddressToBus(pakAddr uint32) (busAddr uint32, err error) {
+	if pakAddr >= 0xF50000 && pakAddr < 0x1_000000 {
+		// mirror bank $F7..FF back down into WRAM because these banks in FX Pak Pro space
+		// are not available on the SNES bus; they are copies of otherwise inaccessible memory
+		// like VRAM, CGRAM, OAM, etc.:
+		busAddr = ((pakAddr - 0xF50000) & 0x01FFFF) + 0x7E0000
+		return
+	} else if pakAddr >= 0xE00000 && pakAddr < 0xF00000 {
+		// game RAM and backup RAM are 128KiB each; mirror the rest of SRAM space down:
+		abs := (pakAddr - 0xE00000) & 0x03FFFF
+		if abs < 0x20000 {
+			// game RAM at banks $70-$71:
+			busAddr = 0x700000 + abs
+		} else {
+			// backup RAM at banks $78-$79:
+			busAddr = 0x780000 + (abs - 0x20000)
+		}
+		return
+	} else if pakAddr < 0xE00000 {
+		// ROM access:
+		// GSU ROM is limited to $20 full banks; banks $40-$5F give a linear mapping without cutting up
+		// in $8000 sized chunks:
+		busAddr = (pakAddr & 0x1FFFFF) + 0x400000
+		return
+	}
+	return 0, util.ErrUnmappedAddress
+}
@@ -0,0 +1,500 @@
+package superfx
+
+import "testing"
+
+func TestPakAddressToBus(t *testing.T) {
+	type args struct {
+		pakAddr uint32
+	}
+	tests := []struct {
+		name string
+		args args
+		want uint32
+	}{
+		{
+			name: "ROM header bank $00",
+			args: args{
+				pakAddr: 0x007FC0,
+			},
+			want: 0x407FC0,
+		},
+		{
+			name: "ROM first byte",
+			args: args{
+				pakAddr: 0x000000,
+			},
+			want: 0x400000,
+		},
+		{
+			name: "ROM bank $00 last byte",
+			args: args{
+				pakAddr: 0x00FFFF,
+			},
+			want: 0x40FFFF,
+		},
+		{
+			name: "ROM bank $01 first byte",
+			args: args{
+				pakAddr: 0x010000,
+			},
+			want: 0x410000,
+		},
+		{
+			name: "ROM last byte 1MiB",
+			args: args{
+				pakAddr: 0x0FFFFF,
+			},
+			want: 0x4FFFFF,
+		},
+		{
+			name: "ROM last byte 2MiB",
+			args: args{
+				pakAddr: 0x1FFFFF,
+			},
+			want: 0x5FFFFF,
+		},
+		{
+			name: "ROM mirror 2MiB",
+			args: args{
+				pakAddr: 0x200000,
+			},
+			want: 0x400000,
+		},
+		{
+			name: "ROM mirror 4MiB",
+			args: args{
+				pakAddr: 0x3F8000,
+			},
+			want: 0x5F8000,
+		},
+		{
+			name: "game RAM first byte",
+			args: args{
+				pakAddr: 0xE00000,
+			},
+			want: 0x700000,
+		},
+		{
+			name: "game RAM bank $0 last byte",
+			args: args{
+				pakAddr: 0xE0FFFF,
+			},
+			want: 0x70FFFF,
+		},
+		{
+			name: "game RAM bank $1 first byte",
+			args: args{
+				pakAddr: 0xE10000,
+			},
+			want: 0x710000,
+		},
+		{
+			name: "game RAM last byte",
+			args: args{
+				pakAddr: 0xE1FFFF,
+			},
+			want: 0x71FFFF,
+		},
+		{
+			name: "backup RAM first byte",
+			args: args{
+				pakAddr: 0xE20000,
+			},
+			want: 0x780000,
+		},
+		{
+			name: "backup RAM last byte",
+			args: args{
+				pakAddr: 0xE3FFFF,
+			},
+			want: 0x79FFFF,
+		},
+		{
+			name: "game RAM mirror",
+			args: args{
+				pakAddr: 0xE40000,
+			},
+			want: 0x700000,
+		},
+		{
+			name: "backup RAM mirror",
+			args: args{
+				pakAddr: 0xEE0000,
+			},
+			want: 0x780000,
+		},
+		{
+			name: "WRAM $00000",
+			args: args{
+				pakAddr: 0xF50000,
+			},
+			want: 0x7E0000,
+		},
+		{
+			name: "WRAM $01FFF",
+			args: args{
+				pakAddr: 0xF51FFF,
+			},
+			want: 0x7E1FFF,
+		},
+		{
+			name: "WRAM $10000",
+			args: args{
+				pakAddr: 0xF60000,
+			},
+			want: 0x7F0000,
+		},
+		{
+			name: "WRAM $1FFFF",
+			args: args{
+				pakAddr: 0xF6FFFF,
+			},
+			want: 0x7FFFFF,
+		},
+		{
+			name: "WRAM mirror 1",
+			args: args{
+				pakAddr: 0xF70000,
+			},
+			want: 0x7E0000,
+		},
+		{
+			name: "WRAM mirror 5",
+			args: args{
+				pakAddr: 0xFF0000,
+			},
+			want: 0x7E0000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PakAddressToBus(tt.args.pakAddr)
+			if err != nil {
+				t.Fatalf("PakAddressToBus() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PakAddressToBus() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusAddressToPak(t *testing.T) {
+	type args struct {
+		busAddr uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    uint32
+		wantErr bool
+	}{
+		{
+			name: "ROM bank $00:8000",
+			args: args{
+				busAddr: 0x008000,
+			},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $00:FFFF",
+			args: args{
+				busAddr: 0x00FFFF,
+			},
+			want: 0x007FFF,
+		},
+		{
+			name: "ROM bank $01:8000",
+			args: args{
+				busAddr: 0x018000,
+			},
+			want: 0x008000,
+		},
+		{
+			name: "ROM bank $3F:FFFF",
+			args: args{
+				busAddr: 0x3FFFFF,
+			},
+			want: 0x1FFFFF,
+		},
+		{
+			name: "ROM bank $80:8000",
+			args: args{
+				busAddr: 0x808000,
+			},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $BF:FFFF",
+			args: args{
+				busAddr: 0xBFFFFF,
+			},
+			want: 0x1FFFFF,
+		},
+		{
+			name: "ROM bank $40:0000",
+			args: args{
+				busAddr: 0x400000,
+			},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $40:FFFF",
+			args: args{
+				busAddr: 0x40FFFF,
+			},
+			want: 0x00FFFF,
+		},
+		{
+			name: "ROM bank $5F:FFFF",
+			args: args{
+				busAddr: 0x5FFFFF,
+			},
+			want: 0x1FFFFF,
+		},
+		{
+			name: "ROM bank $C0:0000",
+			args: args{
+				busAddr: 0xC00000,
+			},
+			want: 0x000000,
+		},
+		{
+			name: "ROM bank $DF:FFFF",
+			args: args{
+				busAddr: 0xDFFFFF,
+			},
+			want: 0x1FFFFF,
+		},
+		{
+			name: "game RAM bank $00:6000",
+			args: args{
+				busAddr: 0x006000,
+			},
+			want: 0xE00000,
+		},
+		{
+			name: "game RAM bank $00:7FFF",
+			args: args{
+				busAddr: 0x007FFF,
+			},
+			want: 0xE01FFF,
+		},
+		{
+			name: "game RAM bank $3F:6000",
+			args: args{
+				busAddr: 0x3F6000,
+			},
+			want: 0xE00000,
+		},
+		{
+			name: "game RAM bank $80:6000",
+			args: args{
+				busAddr: 0x806000,
+			},
+			want: 0xE00000,
+		},
+		{
+			name: "game RAM bank $70:0000",
+			args: args{
+				busAddr: 0x700000,
+			},
+			want: 0xE00000,
+		},
+		{
+			name: "game RAM bank $70:FFFF",
+			args: args{
+				busAddr: 0x70FFFF,
+			},
+			want: 0xE0FFFF,
+		},
+		{
+			name: "game RAM bank $71:0000",
+			args: args{
+				busAddr: 0x710000,
+			},
+			want: 0xE10000,
+		},
+		{
+			name: "game RAM bank $71:FFFF",
+			args: args{
+				busAddr: 0x71FFFF,
+			},
+			want: 0xE1FFFF,
+		},
+		{
+			name: "game RAM bank $F0:0000",
+			args: args{
+				busAddr: 0xF00000,
+			},
+			want: 0xE00000,
+		},
+		{
+			name: "game RAM bank $F1:FFFF",
+			args: args{
+				busAddr: 0xF1FFFF,
+			},
+			want: 0xE1FFFF,
+		},
+		{
+			name: "backup RAM bank $78:0000",
+			args: args{
+				busAddr: 0x780000,
+			},
+			want: 0xE20000,
+		},
+		{
+			name: "backup RAM bank $79:FFFF",
+			args: args{
+				busAddr: 0x79FFFF,
+			},
+			want: 0xE3FFFF,
+		},
+		{
+			name: "WRAM bank $00:0000",
+			args: args{
+				busAddr: 0x000000,
+			},
+			want: 0xF50000,
+		},
+		{
+			name: "WRAM bank $00:1FFF",
+			args: args{
+				busAddr: 0x001FFF,
+			},
+			want: 0xF51FFF,
+		},
+		{
+			name: "WRAM bank $3F:1FFF",
+			args: args{
+				busAddr: 0x3F1FFF,
+			},
+			want: 0xF51FFF,
+		},
+		{
+			name: "WRAM bank $80:0000",
+			args: args{
+				busAddr: 0x800000,
+			},
+			want: 0xF50000,
+		},
+		{
+			name: "WRAM bank $7E:0000",
+			args: args{
+				busAddr: 0x7E0000,
+			},
+			want: 0xF50000,
+		},
+		{
+			name: "WRAM bank $7E:FFFF",
+			args: args{
+				busAddr: 0x7EFFFF,
+			},
+			want: 0xF5FFFF,
+		},
+		{
+			name: "WRAM bank $7F:0000",
+			args: args{
+				busAddr: 0x7F0000,
+			},
+			want: 0xF60000,
+		},
+		{
+			name: "WRAM bank $7F:FFFF",
+			args: args{
+				busAddr: 0x7FFFFF,
+			},
+			want: 0xF6FFFF,
+		},
+		{
+			name: "I/O bank $00:2100",
+			args: args{
+				busAddr: 0x002100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "GSU registers bank $00:3000",
+			args: args{
+				busAddr: 0x003000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmapped bank $60:0000",
+			args: args{
+				busAddr: 0x600000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmapped bank $72:0000",
+			args: args{
+				busAddr: 0x720000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmapped bank $7A:0000",
+			args: args{
+				busAddr: 0x7A0000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmapped bank $E0:0000",
+			args: args{
+				busAddr: 0xE00000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmapped bank $F8:0000",
+			args: args{
+				busAddr: 0xF80000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unmapped bank $FE:0000",
+			args: args{
+				busAddr: 0xFE0000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "out of range",
+			args: args{
+				busAddr: 0x1000000,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BusAddressToPak(tt.args.busAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BusAddressToPak() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BusAddressToPak() = 0x%06x, want 0x%06x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for busAddr := uint32(0); busAddr < 0x1000000; busAddr += 0x0800 {
+		pakAddr, err := BusAddressToPak(busAddr)
+		if err != nil {
+			continue
+		}
+		canon, err := PakAddressToBus(pakAddr)
+		if err != nil {
+			t.Fatalf("PakAddressToBus(0x%06x) error = %v", pakAddr, err)
+		}
+		if got, _ := BusAddressToPak(canon); got != pakAddr {
+			t.Errorf("BusAddressToPak(PakAddressToBus(0x%06x)) = 0x%06x, want 0x%06x", pakAddr, got, pakAddr)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package util
+
+import "fmt"
+
+// FileKind identifies the file a FileOffset refers to
+type FileKind uint8
+
+const (
+	// FileROM is the ROM image without any copier header
+	FileROM FileKind = iota
+	// FileSRAM is the battery-backed save file
+	FileSRAM
+)
+
+func (k FileKind) String() string {
+	switch k {
+	case FileROM:
+		return "ROM"
+	case FileSRAM:
+		return "SRAM"
+	default:
+		return fmt.Sprintf("FileKind(%d)", uint8(k))
+	}
+}
+
+// FileOffset is an offset into the ROM file or SRAM save file
+type FileOffset struct {
+	File   FileKind
+	Offset uint32
+}
+
+func (f FileOffset) String() string {
+	return fmt.Sprintf("%s $%06X", f.File, f.Offset)
+}
+
+// PakToFileOffset converts an FX Pak Pro address to a ROM or SRAM file offset. WRAM and other addresses that are
+// not backed by a file return ErrUnmappedAddress.
+func PakToFileOffset(pakAddr uint32) (f FileOffset, err error) {
+	if pakAddr < 0xE00000 {
+		return FileOffset{File: FileROM, Offset: pakAddr}, nil
+	} else if pakAddr < 0xF00000 {
+		return FileOffset{File: FileSRAM, Offset: pakAddr - 0xE00000}, nil
+	}
+	return FileOffset{}, ErrUnmappedAddress
+}
+
+// FileOffsetToPak converts a ROM or SRAM file offset to an FX Pak Pro address
+func FileOffsetToPak(f FileOffset) (pakAddr uint32, err error) {
+	switch f.File {
+	case FileROM:
+		if f.Offset < 0xE00000 {
+			return f.Offset, nil
+		}
+	case FileSRAM:
+		if f.Offset < 0x100000 {
+			return 0xE00000 + f.Offset, nil
+		}
+	}
+	return 0, ErrUnmappedAddress
+}
+
+// BusAddressToFileOffset translates a bus address to a ROM or SRAM file offset using the given mapping
+func BusAddressToFileOffset(toPak BusToPak, busAddr uint32) (f FileOffset, err error) {
+	var pakAddr uint32
+	if pakAddr, err = toPak(busAddr); err != nil {
+		return
+	}
+	return PakToFileOffset(pakAddr)
+}
+
+// FileOffsetToBus translates a ROM or SRAM file offset to its canonical bus address using the given mapping
+func FileOffsetToBus(toBus PakToBus, f FileOffset) (busAddr uint32, err error) {
+	var pakAddr uint32
+	if pakAddr, err = FileOffsetToPak(f); err != nil {
+		return
+	}
+	return toBus(pakAddr)
+}
@@ -0,0 +1,47 @@
+package util
+
+import "testing"
+
+func TestPakToFileOffset(t *testing.T) {
+	tests := []struct {
+		name    string
+		pakAddr uint32
+		want    FileOffset
+		wantErr bool
+	}{
+		{name: "ROM first byte", pakAddr: 0x000000, want: FileOffset{File: FileROM, Offset: 0x000000}},
+		{name: "ROM last byte", pakAddr: 0xDFFFFF, want: FileOffset{File: FileROM, Offset: 0xDFFFFF}},
+		{name: "SRAM first byte", pakAddr: 0xE00000, want: FileOffset{File: FileSRAM, Offset: 0x000000}},
+		{name: "SRAM last byte", pakAddr: 0xEFFFFF, want: FileOffset{File: FileSRAM, Offset: 0x0FFFFF}},
+		{name: "WRAM", pakAddr: 0xF50000, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PakToFileOffset(tt.pakAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PakToFileOffset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PakToFileOffset() = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			if pakAddr, _ := FileOffsetToPak(got); pakAddr != tt.pakAddr {
+				t.Errorf("FileOffsetToPak() = 0x%06x, want 0x%06x", pakAddr, tt.pakAddr)
+			}
+		})
+	}
+}
+
+func TestFileOffsetToPak_Fail(t *testing.T) {
+	for _, f := range []FileOffset{
+		{File: FileROM, Offset: 0xE00000},
+		{File: FileSRAM, Offset: 0x100000},
+		{File: FileKind(2), Offset: 0},
+	} {
+		if _, err := FileOffsetToPak(f); err != ErrUnmappedAddress {
+			t.Errorf("FileOffsetToPak(%v) error = %v, want %v", f, err, ErrUnmappedAddress)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package util
+
+// Segment is a run of bus addresses that map linearly onto a run of FX Pak Pro addresses
+type Segment struct {
+	Bus  uint32
+	Pak  uint32
+	Size uint32
+}
+
+// BusEnd returns the bus address just past the segment
+func (s Segment) BusEnd() uint32 { return s.Bus + s.Size }
+
+// PakEnd returns the pak address just past the segment
+func (s Segment) PakEnd() uint32 { return s.Pak + s.Size }
+
+const busSize = 0x1000000
+
+// linearRuns calls fn for each maximal run of bus addresses in [busStart, busEnd) that maps linearly to pak space.
+// Pages are assumed linear when their first and last bytes agree, which holds for every mapping at 4KiB granularity;
+// other pages are walked byte by byte.
+func linearRuns(toPak BusToPak, busStart, busEnd uint32, fn func(s Segment)) {
+	for page := busStart &^ (pageSize - 1); page < busEnd; page += pageSize {
+		lo, hi := page, page+pageSize
+		if lo < busStart {
+			lo = busStart
+		}
+		if hi > busEnd {
+			hi = busEnd
+		}
+
+		first, err1 := toPak(page)
+		last, err2 := toPak(page + pageSize - 1)
+		if err1 == nil && err2 == nil && last-first == pageSize-1 {
+			fn(Segment{Bus: lo, Pak: first + (lo - page), Size: hi - lo})
+			continue
+		}
+		if err1 != nil && err2 != nil && pageUnmapped(toPak, page) {
+			continue
+		}
+
+		// walk the page byte by byte:
+		var run Segment
+		for b := lo; b < hi; b++ {
+			p, err := toPak(b)
+			if err != nil {
+				if run.Size > 0 {
+					fn(run)
+					run = Segment{}
+				}
+				continue
+			}
+			if run.Size > 0 && run.PakEnd() == p {
+				run.Size++
+				continue
+			}
+			if run.Size > 0 {
+				fn(run)
+			}
+			run = Segment{Bus: b, Pak: p, Size: 1}
+		}
+		if run.Size > 0 {
+			fn(run)
+		}
+	}
+}
+
+// pageUnmapped samples the page to check that it has no mapped bytes at all
+func pageUnmapped(toPak BusToPak, page uint32) bool {
+	for offs := uint32(0); offs < pageSize; offs += 0x100 {
+		if _, err := toPak(page + offs); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// appendMerged appends s to segs, extending the last segment when s continues it in both bus and pak space
+func appendMerged(segs []Segment, s Segment) []Segment {
+	if n := len(segs); n > 0 && segs[n-1].BusEnd() == s.Bus && segs[n-1].PakEnd() == s.Pak {
+		segs[n-1].Size += s.Size
+		return segs
+	}
+	return append(segs, s)
+}
+
+// Mirrors lists every bus address that maps to the pak address, in ascending order
+func Mirrors(toPak BusToPak, pakAddr uint32) (busAddrs []uint32) {
+	linearRuns(toPak, 0, busSize, func(s Segment) {
+		if pakAddr >= s.Pak && pakAddr < s.PakEnd() {
+			busAddrs = append(busAddrs, s.Bus+(pakAddr-s.Pak))
+		}
+	})
+	return
+}
+
+// MirrorRanges lists every bus segment that maps to part of the pak range [pakAddr, pakAddr+size), in ascending
+// bus order
+func MirrorRanges(toPak BusToPak, pakAddr, size uint32) (segs []Segment) {
+	pakEnd := pakAddr + size
+	linearRuns(toPak, 0, busSize, func(s Segment) {
+		lo, hi := s.Pak, s.PakEnd()
+		if lo < pakAddr {
+			lo = pakAddr
+		}
+		if hi > pakEnd {
+			hi = pakEnd
+		}
+		if lo >= hi {
+			return
+		}
+		segs = appendMerged(segs, Segment{Bus: s.Bus + (lo - s.Pak), Pak: lo, Size: hi - lo})
+	})
+	return
+}
+
+// SplitBusRange splits the bus range [busAddr, busAddr+size) into segments that each map contiguously to pak space.
+// Unmapped parts of the range are left out.
+func SplitBusRange(toPak BusToPak, busAddr, size uint32) (segs []Segment) {
+	busEnd := busAddr + size
+	if busEnd > busSize || busEnd < busAddr {
+		busEnd = busSize
+	}
+	linearRuns(toPak, busAddr, busEnd, func(s Segment) {
+		segs = appendMerged(segs, s)
+	})
+	return
+}
@@ -0,0 +1,137 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testBusToPak maps banks $00-$3F and $80-$BF:8000-FFFF LoROM-style to ROM, mirrors WRAM at $0000-$1FFF of those
+// banks and $7E-$7F, and folds $40:0000-$40:00FF onto a 16 byte SRAM.
+func testBusToPak(busAddr uint32) (uint32, error) {
+	bank := busAddr >> 16
+	offs := busAddr & 0xFFFF
+	if bank >= 0x7E && bank < 0x80 {
+		return busAddr - 0x7E0000 + 0xF50000, nil
+	}
+	if bank == 0x40 && offs < 0x100 {
+		return 0xE00000 + offs&0x0F, nil
+	}
+	if bank&0x40 != 0 {
+		return 0, ErrUnmappedAddress
+	}
+	if offs&0x8000 != 0 {
+		return BankToLinear(busAddr & 0x3F7FFF), nil
+	}
+	if offs < 0x2000 {
+		return 0xF50000 + offs, nil
+	}
+	return 0, ErrUnmappedAddress
+}
+
+func TestMirrors(t *testing.T) {
+	got := Mirrors(testBusToPak, 0x018000)
+	want := []uint32{0x038000, 0x838000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Mirrors(ROM) = %06x, want %06x", got, want)
+	}
+
+	got = Mirrors(testBusToPak, 0xF51234)
+	if len(got) != 129 {
+		t.Fatalf("len(Mirrors(WRAM)) = %d, want 129", len(got))
+	}
+	if got[0] != 0x001234 || got[64] != 0x7E1234 || got[128] != 0xBF1234 {
+		t.Errorf("Mirrors(WRAM) = %06x..., want 001234, 7E1234 at 64, BF1234 at 128", got[:2])
+	}
+
+	got = Mirrors(testBusToPak, 0xE00003)
+	want = []uint32{}
+	for offs := uint32(0x03); offs < 0x100; offs += 0x10 {
+		want = append(want, 0x400000|offs)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Mirrors(SRAM) = %06x, want %06x", got, want)
+	}
+
+	if got = Mirrors(testBusToPak, 0xD00000); len(got) != 0 {
+		t.Errorf("Mirrors(unmapped) = %06x, want none", got)
+	}
+}
+
+func TestMirrorRanges(t *testing.T) {
+	got := MirrorRanges(testBusToPak, 0x007F00, 0x200)
+	want := []Segment{
+		{Bus: 0x00FF00, Pak: 0x007F00, Size: 0x100},
+		{Bus: 0x018000, Pak: 0x008000, Size: 0x100},
+		{Bus: 0x80FF00, Pak: 0x007F00, Size: 0x100},
+		{Bus: 0x818000, Pak: 0x008000, Size: 0x100},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MirrorRanges() = %+v, want %+v", got, want)
+	}
+
+	got = MirrorRanges(testBusToPak, 0xF60000, 0x10000)
+	want = []Segment{{Bus: 0x7F0000, Pak: 0xF60000, Size: 0x10000}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MirrorRanges() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitBusRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		busAddr uint32
+		size    uint32
+		want    []Segment
+	}{
+		{
+			name:    "within a bank",
+			busAddr: 0x008010,
+			size:    0x20,
+			want:    []Segment{{Bus: 0x008010, Pak: 0x000010, Size: 0x20}},
+		},
+		{
+			name:    "across a bank boundary",
+			busAddr: 0x00FFF0,
+			size:    0x8020,
+			want: []Segment{
+				{Bus: 0x00FFF0, Pak: 0x007FF0, Size: 0x10},
+				{Bus: 0x010000, Pak: 0xF50000, Size: 0x2000},
+				{Bus: 0x018000, Pak: 0x008000, Size: 0x10},
+			},
+		},
+		{
+			name:    "linear across WRAM banks",
+			busAddr: 0x7EF000,
+			size:    0x2000,
+			want:    []Segment{{Bus: 0x7EF000, Pak: 0xF5F000, Size: 0x2000}},
+		},
+		{
+			name:    "non-linear page",
+			busAddr: 0x40000E,
+			size:    0x4,
+			want: []Segment{
+				{Bus: 0x40000E, Pak: 0xE0000E, Size: 0x2},
+				{Bus: 0x400010, Pak: 0xE00000, Size: 0x2},
+			},
+		},
+		{
+			name:    "unmapped",
+			busAddr: 0x412345,
+			size:    0x100,
+			want:    nil,
+		},
+		{
+			name:    "clipped to the end of the bus",
+			busAddr: 0xBFFFF0,
+			size:    0x1000000,
+			want:    []Segment{{Bus: 0xBFFFF0, Pak: 0x1FFFF0, Size: 0x10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitBusRange(testBusToPak, tt.busAddr, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitBusRange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
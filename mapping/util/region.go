@@ -0,0 +1,247 @@
+package util
+
+import "fmt"
+
+// BusToPak translates a SNES bus address to FX Pak Pro address space
+type BusToPak func(busAddr uint32) (pakAddr uint32, err error)
+
+// PakToBus translates an FX Pak Pro address to its canonical SNES bus address
+type PakToBus func(pakAddr uint32) (busAddr uint32, err error)
+
+// RegionKind classifies what kind of memory a bus address accesses
+type RegionKind uint8
+
+const (
+	RegionOpenBus RegionKind = iota
+	RegionROM
+	RegionSRAM
+	RegionWRAM
+	// RegionPPU is the PPU register block at $2100-$213F
+	RegionPPU
+	// RegionAPU is the APU I/O port block at $2140-$217F
+	RegionAPU
+	// RegionCPU covers the WRAM port at $2180-$2183, joypad serial ports and CPU registers at $4200-$421F
+	RegionCPU
+	// RegionDMA is the DMA channel register block at $4300-$437F
+	RegionDMA
+	// RegionExpansion covers cartridge coprocessor registers and memory in the I/O area
+	RegionExpansion
+)
+
+var regionKindNames = map[RegionKind]string{
+	RegionOpenBus:   "open bus",
+	RegionROM:       "ROM",
+	RegionSRAM:      "SRAM",
+	RegionWRAM:      "WRAM",
+	RegionPPU:       "PPU",
+	RegionAPU:       "APU",
+	RegionCPU:       "CPU",
+	RegionDMA:       "DMA",
+	RegionExpansion: "expansion",
+}
+
+func (k RegionKind) String() string {
+	if s, ok := regionKindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("RegionKind(%d)", uint8(k))
+}
+
+// IsMemory reports whether the region kind is backed by memory addressable in FX Pak Pro space
+func (k RegionKind) IsMemory() bool {
+	return k == RegionROM || k == RegionSRAM || k == RegionWRAM
+}
+
+// IsMMIO reports whether the region kind is a block of hardware registers
+func (k RegionKind) IsMMIO() bool {
+	return k >= RegionPPU && k <= RegionExpansion
+}
+
+// Region describes the contiguous region of the SNES bus that an address falls in
+type Region struct {
+	Kind RegionKind
+	// Base is the first bus address of the region
+	Base uint32
+	// Size is the length of the region in bytes
+	Size uint32
+	// Pak is the FX Pak Pro address of the queried bus address; only valid for memory kinds
+	Pak uint32
+	// Mirror is the canonical bus address of the queried bus address
+	Mirror uint32
+	// Mirrored is true when the queried bus address is not the canonical address
+	Mirrored bool
+}
+
+func (r Region) String() string {
+	return fmt.Sprintf("%s $%06X-$%06X", r.Kind, r.Base, r.Base+r.Size-1)
+}
+
+// Contains reports whether the bus address is within the region
+func (r Region) Contains(busAddr uint32) bool {
+	return busAddr >= r.Base && busAddr-r.Base < r.Size
+}
+
+// IORange is a block of registers in the $2000-$5FFF I/O area; End is exclusive
+type IORange struct {
+	Kind  RegionKind
+	Start uint16
+	End   uint16
+}
+
+var systemIO = []IORange{
+	{RegionPPU, 0x2100, 0x2140},
+	{RegionAPU, 0x2140, 0x2180},
+	{RegionCPU, 0x2180, 0x2184},
+	{RegionCPU, 0x4016, 0x4018},
+	{RegionCPU, 0x4200, 0x4220},
+	{RegionDMA, 0x4300, 0x4380},
+}
+
+// PakRegionKind classifies an FX Pak Pro address
+func PakRegionKind(pakAddr uint32) RegionKind {
+	if pakAddr < 0xE00000 {
+		return RegionROM
+	} else if pakAddr < 0xF00000 {
+		return RegionSRAM
+	} else if pakAddr >= 0xF50000 && pakAddr < 0xF70000 {
+		return RegionWRAM
+	}
+	return RegionOpenBus
+}
+
+// IsSystemArea reports whether the bus address is in the $2000-$5FFF I/O area of banks $00-$3F and $80-$BF
+func IsSystemArea(busAddr uint32) bool {
+	offs := busAddr & 0xFFFF
+	return busAddr&0x400000 == 0 && offs >= 0x2000 && offs < 0x6000
+}
+
+// Mapping bundles the translation functions of a mapping package to classify bus addresses
+type Mapping struct {
+	BusToPak BusToPak
+	PakToBus PakToBus
+	// Expansion lists coprocessor register blocks in the I/O area; these take precedence over system registers
+	Expansion []IORange
+}
+
+const pageSize = 0x1000
+
+// Region classifies the bus address and finds the bounds of the region containing it
+func (m Mapping) Region(busAddr uint32) (r Region) {
+	busAddr &= 0xFFFFFF
+	if IsSystemArea(busAddr) {
+		return m.ioRegion(busAddr)
+	}
+
+	r.Kind, r.Pak = m.classify(busAddr)
+	r.Mirror = busAddr
+	if r.Kind.IsMemory() {
+		if canon, err := m.PakToBus(r.Pak); err == nil {
+			r.Mirror = canon
+			r.Mirrored = canon != busAddr
+		}
+	}
+
+	// extend the region page by page while the mapping stays linear:
+	page := busAddr &^ (pageSize - 1)
+	lo, hi := page, page+pageSize
+	for lo > 0 {
+		k, p, ok := m.page(lo - pageSize)
+		if !ok || !continues(k, p, r.Kind, r.Pak-(busAddr-lo)-pageSize) {
+			break
+		}
+		lo -= pageSize
+	}
+	for hi < 0x1000000 {
+		k, p, ok := m.page(hi)
+		if !ok || !continues(k, p, r.Kind, r.Pak+(hi-busAddr)) {
+			break
+		}
+		hi += pageSize
+	}
+
+	r.Base = lo
+	r.Size = hi - lo
+	return
+}
+
+// continues reports whether a page of kind k starting at pak address p extends a region of the given kind whose
+// mapping would place that page at pak address want
+func continues(k RegionKind, p uint32, kind RegionKind, want uint32) bool {
+	if k != kind {
+		return false
+	}
+	if !kind.IsMemory() {
+		return true
+	}
+	return p == want
+}
+
+func (m Mapping) classify(busAddr uint32) (RegionKind, uint32) {
+	pakAddr, err := m.BusToPak(busAddr)
+	if err != nil {
+		return RegionOpenBus, 0
+	}
+	return PakRegionKind(pakAddr), pakAddr
+}
+
+// page classifies a page for region scanning; pages in the I/O area never join a region
+func (m Mapping) page(busAddr uint32) (kind RegionKind, pakAddr uint32, ok bool) {
+	if IsSystemArea(busAddr) {
+		return
+	}
+	kind, pakAddr = m.classify(busAddr)
+	ok = true
+	return
+}
+
+func (m Mapping) ioRegion(busAddr uint32) (r Region) {
+	bank := busAddr &^ 0xFFFF
+	offs := uint16(busAddr)
+
+	// all system banks mirror the I/O area of bank $00:
+	r.Mirror = uint32(offs)
+	r.Mirrored = bank != 0
+
+	for _, ranges := range [][]IORange{m.Expansion, systemIO} {
+		for _, io := range ranges {
+			if offs >= io.Start && offs < io.End {
+				r.Kind = io.Kind
+				r.Base = bank | uint32(io.Start)
+				r.Size = uint32(io.End - io.Start)
+				return
+			}
+		}
+	}
+
+	// open bus between register blocks:
+	start, end := uint16(0x2000), uint16(0x6000)
+	for _, ranges := range [][]IORange{m.Expansion, systemIO} {
+		for _, io := range ranges {
+			if io.End <= offs && io.End > start {
+				start = io.End
+			}
+			if io.Start > offs && io.Start < end {
+				end = io.Start
+			}
+		}
+	}
+	r.Kind = RegionOpenBus
+	r.Base = bank | uint32(start)
+	r.Size = uint32(end - start)
+	// open bus is not a mirror of anything:
+	r.Mirror = busAddr
+	r.Mirrored = false
+	return
+}
+
+// BusAddressToROMOffset translates a bus address to an offset into the ROM file using the given mapping
+func BusAddressToROMOffset(toPak BusToPak, busAddr uint32) (offset uint32, err error) {
+	var f FileOffset
+	if f, err = BusAddressToFileOffset(toPak, busAddr); err != nil {
+		return
+	}
+	if f.File != FileROM {
+		return 0, ErrUnmappedAddress
+	}
+	return f.Offset, nil
+}
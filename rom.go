@@ -10,27 +10,193 @@ type ROM struct {
 	Name     string
 	Contents []byte
 
+	// Copier is the copier header stripped from the start of the file, if any:
+	Copier *CopierHeader
+
 	HeaderOffset uint32
 	Header       Header
+
+	// Layout is the detected header location:
+	Layout Layout
+	// Confidence is the detected header's score relative to the maximum possible score, from 0.0 to 1.0:
+	Confidence float64
+}
+
+// Layout identifies where in the ROM file the header was found
+type Layout uint8
+
+const (
+	LayoutLoROM Layout = iota
+	LayoutHiROM
+	LayoutExHiROM
+)
+
+var layoutNames = map[Layout]string{
+	LayoutLoROM:   "LoROM",
+	LayoutHiROM:   "HiROM",
+	LayoutExHiROM: "ExHiROM",
+}
+
+func (l Layout) String() string {
+	if s, ok := layoutNames[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("Layout(%d)", uint8(l))
+}
+
+// HeaderOffset returns the file offset of the $FFB0 header for this layout
+func (l Layout) HeaderOffset() uint32 {
+	switch l {
+	case LayoutHiROM:
+		return 0x00FFB0
+	case LayoutExHiROM:
+		return 0x40FFB0
+	default:
+		return 0x007FB0
+	}
 }
 
+// maxHeaderScore is the highest score that scoreHeaderAt can award:
+const maxHeaderScore = 25 + 2 + 8
+
 func NewROM(name string, contents []byte) (r *ROM, err error) {
+	var copier *CopierHeader
+	if detectCopierHeader(contents) {
+		if copier, err = ParseCopierHeader(contents); err != nil {
+			return nil, err
+		}
+		contents = contents[CopierHeaderSize:]
+	}
+
 	if len(contents) < 0x8000 {
 		return nil, fmt.Errorf("ROM file not big enough to contain SNES header")
 	}
 
-	headerOffset := uint32(0x007FB0)
-
 	r = &ROM{
-		Name:         name,
-		Contents:     contents,
-		HeaderOffset: headerOffset,
+		Name:     name,
+		Contents: contents,
+		Copier:   copier,
+	}
+
+	// probe each candidate header location and keep the best scoring one; ties favor LoROM:
+	bestScore := -1
+	for _, layout := range []Layout{LayoutLoROM, LayoutHiROM, LayoutExHiROM} {
+		score, ok := r.scoreHeaderAt(layout.HeaderOffset())
+		if !ok {
+			continue
+		}
+		if score > bestScore {
+			bestScore = score
+			r.Layout = layout
+		}
+	}
+
+	if bestScore < 0 {
+		bestScore = 0
+	}
+	r.HeaderOffset = r.Layout.HeaderOffset()
+	r.Confidence = float64(bestScore) / maxHeaderScore
+	if r.Confidence > 1 {
+		r.Confidence = 1
 	}
 
 	err = r.ReadHeader()
 	return
 }
 
+// scoreHeaderAt scores the header found at the given file offset; ok is false if the header would lie outside the ROM
+func (r *ROM) scoreHeaderAt(addr uint32) (score int, ok bool) {
+	if uint64(addr)+0x50 > uint64(len(r.Contents)) {
+		return 0, false
+	}
+
+	var h Header
+	if err := h.ReadHeader(bytes.NewReader(r.Contents[addr : addr+0x50])); err != nil {
+		return 0, false
+	}
+
+	score = h.Score(addr)
+	if score == 0 {
+		// RESET vector is invalid:
+		return 0, true
+	}
+
+	// ROM size byte should agree with the actual file size:
+	size := uint64(len(r.Contents))
+	if h.ROMSize >= 0x07 && h.ROMSize < 0x0E {
+		declared := uint64(h.ROMSizeBytes())
+		if declared >= size && declared < size<<1 {
+			score += 2
+		} else if declared<<1 < size || declared > size<<1 {
+			score--
+		}
+	}
+
+	// first opcode executed upon reset is a good indication of a valid header:
+	resetOffset := (addr &^ 0x7FFF) | uint32(h.EmulatedVectors.RESET&0x7FFF)
+	if resetOffset < uint32(len(r.Contents)) {
+		score += resetOpcodeScore(r.Contents[resetOffset])
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	return score, true
+}
+
+// resetOpcodeScore rates how likely the opcode is to be the first instruction executed upon reset
+func resetOpcodeScore(opcode byte) int {
+	switch opcode {
+	case 0x78, // sei
+		0x18, // clc
+		0x38, // sec
+		0x9C, // stz abs
+		0x4C, // jmp abs
+		0x5C: // jml long
+		return 8
+	case 0xC2, // rep
+		0xE2, // sep
+		0xAD, // lda abs
+		0xAE, // ldx abs
+		0xAC, // ldy abs
+		0xAF, // lda long
+		0xA9, // lda imm
+		0xA2, // ldx imm
+		0xA0, // ldy imm
+		0x20, // jsr abs
+		0x22: // jsl long
+		return 4
+	case 0x40, // rti
+		0x60, // rts
+		0x6B, // rtl
+		0xCD, // cmp abs
+		0xEC, // cpx abs
+		0xCC: // cpy abs
+		return -4
+	case 0x00, // brk
+		0x02, // cop
+		0xDB, // stp
+		0x42, // wdm
+		0xFF: // sbc long,x
+		return -8
+	}
+	return 0
+}
+
+// WriteTo writes out the ROM file including its copier header, if any
+func (r *ROM) WriteTo(w io.Writer) (n int64, err error) {
+	if r.Copier != nil {
+		if n, err = r.Copier.WriteTo(w); err != nil {
+			return
+		}
+	}
+
+	var m int
+	m, err = w.Write(r.Contents)
+	n += int64(m)
+	return
+}
+
 func (r *ROM) ReadHeader() (err error) {
 	// Read SNES header:
 	b := bytes.NewReader(r.Contents[r.HeaderOffset : r.HeaderOffset+0x50])
@@ -54,60 +220,114 @@ func (r *ROM) WriteHeader() (err error) {
 	return
 }
 
-type alwaysError struct{}
+// BusMapper translates SNES bus addresses into FX Pak Pro address space, e.g. the BusAddressToPak method of the
+// mapping.Mapper for the ROM's header
+type BusMapper func(busAddr uint32) (pakAddr uint32, err error)
+
+// busOffset translates a bus address to an offset into Contents; ok is false if the address does not map to ROM
+func (r *ROM) busOffset(toPak BusMapper, busAddr uint32) (offs uint32, ok bool) {
+	pakAddr, err := toPak(busAddr)
+	if err != nil {
+		return 0, false
+	}
+	// FX Pak Pro space maps ROM to $000000-$DFFFFF:
+	if pakAddr >= 0xE00000 || pakAddr >= uint32(len(r.Contents)) {
+		return 0, false
+	}
+	return pakAddr, true
+}
+
+// BusFile provides access to ROM contents addressed by 24-bit SNES bus addresses which are translated through a
+// BusMapper; mapping.BusFile selects the one that matches the ROM's header. Accesses stop short with
+// io.ErrUnexpectedEOF at the first address that does not map to ROM contents, e.g. WRAM, SRAM, I/O registers or past
+// the end of the ROM.
+type BusFile struct {
+	r     *ROM
+	toPak BusMapper
+	pos   int64
+}
 
-func (alwaysError) Read(p []byte) (int, error) {
-	return 0, io.ErrUnexpectedEOF
+// BusFile returns a BusFile that translates through toPak, positioned at the given bus address
+func (r *ROM) BusFile(toPak BusMapper, busAddr uint32) *BusFile {
+	return &BusFile{r: r, toPak: toPak, pos: int64(busAddr & 0xFFFFFF)}
 }
 
-func (alwaysError) Write(p []byte) (n int, err error) {
-	return 0, io.ErrUnexpectedEOF
+func (r *ROM) BusReader(toPak BusMapper, busAddr uint32) *BusFile {
+	return r.BusFile(toPak, busAddr)
 }
 
-var alwaysErrorInstance = &alwaysError{}
+func (r *ROM) BusWriter(toPak BusMapper, busAddr uint32) *BusFile {
+	return r.BusFile(toPak, busAddr)
+}
 
-func (r *ROM) BusReader(busAddr uint32) io.Reader {
-	page := busAddr & 0xFFFF
-	if page < 0x8000 {
-		return alwaysErrorInstance
+func (f *BusFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, fmt.Errorf("snes: negative bus address")
 	}
 
-	// Return a reader over the ROM contents up to the next bank to prevent accidental overflow:
-	bank := busAddr >> 16
-	pcStart := (bank << 15) | (page - 0x8000)
-	pcEnd := (bank << 15) | 0x7FFF
-	return bytes.NewReader(r.Contents[pcStart:pcEnd])
+	for n < len(p) {
+		a := off + int64(n)
+		if a > 0xFFFFFF {
+			return n, io.EOF
+		}
+		o, ok := f.r.busOffset(f.toPak, uint32(a))
+		if !ok {
+			return n, io.ErrUnexpectedEOF
+		}
+		p[n] = f.r.Contents[o]
+		n++
+	}
+	return
 }
 
-type busWriter struct {
-	r       *ROM
-	busAddr uint32
-	start   uint32
-	end     uint32
-	o       uint32
-}
+func (f *BusFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, fmt.Errorf("snes: negative bus address")
+	}
 
-func (w *busWriter) Write(p []byte) (n int, err error) {
-	if uint32(len(p)) >= w.o+w.end {
-		err = io.ErrUnexpectedEOF
-		return
+	for n < len(p) {
+		a := off + int64(n)
+		if a > 0xFFFFFF {
+			return n, io.ErrShortWrite
+		}
+		o, ok := f.r.busOffset(f.toPak, uint32(a))
+		if !ok {
+			return n, io.ErrUnexpectedEOF
+		}
+		f.r.Contents[o] = p[n]
+		n++
 	}
+	return
+}
 
-	n = copy(w.r.Contents[w.o+w.start:w.end], p)
-	w.o += uint32(n)
+func (f *BusFile) Read(p []byte) (n int, err error) {
+	n, err = f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	return
+}
 
+func (f *BusFile) Write(p []byte) (n int, err error) {
+	n, err = f.WriteAt(p, f.pos)
+	f.pos += int64(n)
 	return
 }
 
-func (r *ROM) BusWriter(busAddr uint32) io.Writer {
-	page := busAddr & 0xFFFF
-	if page < 0x8000 {
-		return alwaysErrorInstance
+// Seek sets the bus address for the next Read or Write; io.SeekEnd is relative to the end of the 24-bit bus
+func (f *BusFile) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.pos + offset
+	case io.SeekEnd:
+		pos = 0x1000000 + offset
+	default:
+		return f.pos, fmt.Errorf("snes: invalid whence")
 	}
-
-	// Return a reader over the ROM contents up to the next bank to prevent accidental overflow:
-	bank := busAddr >> 16
-	pcStart := (bank << 15) | (page - 0x8000)
-	pcEnd := (bank << 15) | 0x7FFF
-	return &busWriter{r, busAddr, pcStart, pcEnd, 0}
+	if pos < 0 || pos > 0x1000000 {
+		return f.pos, fmt.Errorf("snes: seek to bus address %x out of range", pos)
+	}
+	f.pos = pos
+	return pos, nil
 }
@@ -7,6 +7,9 @@ import (
 	"errors"
 	"io"
 	"testing"
+
+	"github.com/alttpo/snes/mapping/hirom"
+	"github.com/alttpo/snes/mapping/lorom"
 )
 
 func sampleROM() []byte {
@@ -42,7 +45,7 @@ func TestROM_BusReader_Success(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	r := rom.BusReader(0x00FFEA)
+	r := rom.BusReader(lorom.BusAddressToPak, 0x00FFEA)
 	p := uint16(0)
 	err = binary.Read(r, binary.LittleEndian, &p)
 	if err != nil {
@@ -60,11 +63,11 @@ func TestROM_BusReader_Fail_Unmapped(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	r := rom.BusReader(0x007FFF)
+	r := rom.BusReader(lorom.BusAddressToPak, 0x007FFF)
 	p := uint16(0)
 	err = binary.Read(r, binary.LittleEndian, &p)
 	if err != io.ErrUnexpectedEOF {
-		t.Fatalf("expected fail with EOF but got: %v", err)
+		t.Fatalf("expected fail with unexpected EOF but got: %v", err)
 	}
 }
 
@@ -75,11 +78,20 @@ func TestROM_BusReader_Fail_Boundary(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	r := rom.BusReader(0x00FFFF)
+	// last byte of the bank is readable:
+	r := rom.BusReader(lorom.BusAddressToPak, 0x00FFFF)
+	b := byte(0)
+	err = binary.Read(r, binary.LittleEndian, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reading across into $01:0000 (WRAM) must fail:
+	r = rom.BusReader(lorom.BusAddressToPak, 0x00FFFF)
 	p := uint16(0)
 	err = binary.Read(r, binary.LittleEndian, &p)
-	if !errors.Is(err, io.EOF) {
-		t.Fatalf("expected fail with EOF but got: %v", err)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected fail with unexpected EOF but got: %v", err)
 	}
 }
 
@@ -91,7 +103,7 @@ func TestROM_BusWriter_Bank00_Success(t *testing.T) {
 	}
 
 	// write to bus writer:
-	w := rom.BusWriter(0x00FFEA)
+	w := rom.BusWriter(lorom.BusAddressToPak, 0x00FFEA)
 	p := uint16(0x80c8)
 	err = binary.Write(w, binary.LittleEndian, &p)
 	if err != nil {
@@ -124,7 +136,7 @@ func TestROM_BusWriter_Bank01_Success(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	w := rom.BusWriter(0x018000)
+	w := rom.BusWriter(lorom.BusAddressToPak, 0x018000)
 	p := byte(0x80)
 	err = binary.Write(w, binary.LittleEndian, &p)
 	if err != nil {
@@ -134,3 +146,159 @@ func TestROM_BusWriter_Bank01_Success(t *testing.T) {
 		t.Fatal("expected NMI vector at $FFEA")
 	}
 }
+
+func sampleHeader(mapMode byte) []byte {
+	header := make([]byte, 0x50)
+	hex.Decode(
+		header,
+		[]byte("018d2401e2306bffffffffffffffffff"+
+			"544845204c4547454e44204f46205a45"+
+			"4c4441202020020a03010100f2500daf"+
+			"ffffffff2c82ffff2c82c9800080d882"+
+			"ffffffff2c822c822c822c820080d882"),
+	)
+	header[0x25] = mapMode
+	return header
+}
+
+func TestNewROM_Layout(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		offset     uint32
+		mapMode    byte
+		wantLayout Layout
+	}{
+		{
+			name:       "LoROM",
+			size:       0x10000,
+			offset:     0x007FB0,
+			mapMode:    0x20,
+			wantLayout: LayoutLoROM,
+		},
+		{
+			name:       "HiROM",
+			size:       0x10000,
+			offset:     0x00FFB0,
+			mapMode:    0x21,
+			wantLayout: LayoutHiROM,
+		},
+		{
+			name:       "ExHiROM",
+			size:       0x410000,
+			offset:     0x40FFB0,
+			mapMode:    0x25,
+			wantLayout: LayoutExHiROM,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := make([]byte, tt.size)
+			copy(contents[tt.offset:], sampleHeader(tt.mapMode))
+			// place a SEI at the RESET vector:
+			contents[tt.offset&^0x7FFF] = 0x78
+
+			rom, err := NewROM("", contents)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rom.Layout != tt.wantLayout {
+				t.Errorf("Layout = %v, want %v", rom.Layout, tt.wantLayout)
+			}
+			if rom.HeaderOffset != tt.offset {
+				t.Errorf("HeaderOffset = %06x, want %06x", rom.HeaderOffset, tt.offset)
+			}
+			if rom.Header.MapMode != tt.mapMode {
+				t.Errorf("Header.MapMode = %02x, want %02x", rom.Header.MapMode, tt.mapMode)
+			}
+			if rom.Confidence <= 0 || rom.Confidence > 1 {
+				t.Errorf("Confidence = %v, want (0, 1]", rom.Confidence)
+			}
+		})
+	}
+}
+
+func TestROM_BusReader_HiROM(t *testing.T) {
+	contents := make([]byte, 0x10000)
+	copy(contents[0xFFB0:], sampleHeader(0x21))
+	contents[0x8000] = 0x78
+	contents[0x1234] = 0x5A
+
+	rom, err := NewROM("", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		busAddr uint32
+		want    byte
+	}{
+		{name: "bank $C0 linear", busAddr: 0xC01234, want: 0x5A},
+		{name: "bank $40 linear", busAddr: 0x401234, want: 0x5A},
+		{name: "bank $C0 RESET", busAddr: 0xC08000, want: 0x78},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := []byte{0}
+			n, err := rom.BusReader(hirom.BusAddressToPak, 0).ReadAt(p, int64(tt.busAddr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 || p[0] != tt.want {
+				t.Errorf("ReadAt() = %02x, want %02x", p[0], tt.want)
+			}
+		})
+	}
+
+	// HiROM has no ROM in the lower half of bank $00:
+	if _, err = rom.BusReader(hirom.BusAddressToPak, 0).ReadAt([]byte{0}, 0x001234); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected fail with unexpected EOF but got: %v", err)
+	}
+}
+
+func TestROM_BusFile_Seek(t *testing.T) {
+	rom, err := NewROM("", sampleROM())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := rom.BusFile(lorom.BusAddressToPak, 0)
+	if _, err = f.Seek(0x00FFEA, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	p := uint16(0)
+	if err = binary.Read(f, binary.LittleEndian, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != 0x80c9 {
+		t.Fatal("expected NMI vector at $FFEA")
+	}
+
+	// writes go through the same mapping and advance the position:
+	var pos int64
+	if pos, err = f.Seek(-2, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	if pos != 0x00FFEA {
+		t.Fatalf("Seek() = %06x, want %06x", pos, 0x00FFEA)
+	}
+	if _, err = f.Write([]byte{0xc8, 0x80}); err != nil {
+		t.Fatal(err)
+	}
+	if rom.Contents[0x7FEA] != 0xc8 {
+		t.Fatal("expected write to land at ROM offset $7FEA")
+	}
+
+	// WriterAt into the FastROM mirror:
+	if _, err = f.WriteAt([]byte{0xAB}, 0x808000); err != nil {
+		t.Fatal(err)
+	}
+	if rom.Contents[0] != 0xAB {
+		t.Fatal("expected write to land at ROM offset $0000")
+	}
+
+	if _, err = f.Seek(1, io.SeekEnd); err == nil {
+		t.Fatal("expected seek past end of bus to fail")
+	}
+}
@@ -0,0 +1,42 @@
+package timing
+
+// CPU bus access speeds in master clocks
+const (
+	FastClocks  = 6
+	SlowClocks  = 8
+	XSlowClocks = 12
+)
+
+// AccessClocks returns the master clocks a CPU access to the bus address takes; fastROM is MEMSEL ($420D) bit 0,
+// which speeds up ROM in banks $80-$FF
+func AccessClocks(busAddr uint32, fastROM bool) uint64 {
+	bank := (busAddr >> 16) & 0xFF
+	offs := busAddr & 0xFFFF
+	if bank&0x40 != 0 {
+		// $40-$7F and $C0-$FF:
+		if bank&0x80 != 0 && fastROM {
+			return FastClocks
+		}
+		return SlowClocks
+	}
+
+	// $00-$3F and $80-$BF:
+	switch {
+	case offs >= 0x8000:
+		if bank&0x80 != 0 && fastROM {
+			return FastClocks
+		}
+		return SlowClocks
+	case offs >= 0x6000:
+		return SlowClocks
+	case offs >= 0x4200:
+		return FastClocks
+	case offs >= 0x4000:
+		// serial joypad registers:
+		return XSlowClocks
+	case offs >= 0x2000:
+		return FastClocks
+	default:
+		return SlowClocks
+	}
+}
@@ -0,0 +1,41 @@
+package timing
+
+import "testing"
+
+func TestAccessClocks(t *testing.T) {
+	tests := []struct {
+		addr    uint32
+		fastROM bool
+		want    uint64
+	}{
+		{0x000000, false, SlowClocks},
+		{0x7E0000, false, SlowClocks},
+		{0x002100, false, FastClocks},
+		{0x004016, false, XSlowClocks},
+		{0x004200, false, FastClocks},
+		{0x806000, true, SlowClocks},
+		{0x008000, true, SlowClocks},
+		{0x808000, false, SlowClocks},
+		{0x808000, true, FastClocks},
+		{0xC00000, true, FastClocks},
+		{0x400000, true, SlowClocks},
+		{0xFFFFFF, false, SlowClocks},
+	}
+	for _, tt := range tests {
+		if got := AccessClocks(tt.addr, tt.fastROM); got != tt.want {
+			t.Errorf("AccessClocks($%06X, %v) = %d, want %d", tt.addr, tt.fastROM, got, tt.want)
+		}
+	}
+}
+
+func TestStandard(t *testing.T) {
+	if got := NTSC.ClocksPerFrame(); got != 357368 {
+		t.Errorf("NTSC clocks per frame = %d", got)
+	}
+	if got := PAL.ClocksPerFrame(); got != 425568 {
+		t.Errorf("PAL clocks per frame = %d", got)
+	}
+	if NTSC.Frame() != Frame || PAL.Frame() != PALFrame {
+		t.Errorf("frame durations")
+	}
+}
@@ -0,0 +1,73 @@
+package timing
+
+import "time"
+
+// PALFrame = 21,281,370 / 425,568 ~= 50.0069789 frames / sec ~= 19,997,208.83 ns / frame
+const PALFrame = time.Nanosecond * 19_997_209
+
+// Beam timing in master clocks
+const (
+	ClocksPerDot  = 4
+	ClocksPerLine = 1364
+	DotsPerLine   = ClocksPerLine / ClocksPerDot
+
+	// HBlankStartDot is the dot at which horizontal blank and HDMA begin; HBlankEndDot the dot at which it ends
+	HBlankStartDot = 274
+	HBlankEndDot   = 1
+)
+
+// Standard is the video standard of the console, which sets its master clock and frame length
+type Standard uint8
+
+const (
+	NTSC Standard = iota
+	PAL
+)
+
+func (s Standard) String() string {
+	switch s {
+	case NTSC:
+		return "NTSC"
+	case PAL:
+		return "PAL"
+	default:
+		return "unknown"
+	}
+}
+
+// MasterClock returns the master clock frequency in Hz
+func (s Standard) MasterClock() float64 {
+	if s == PAL {
+		return 21_281_370
+	}
+	return 21_477_272.727272
+}
+
+// LinesPerFrame returns the scanlines in a non-interlaced frame
+func (s Standard) LinesPerFrame() uint16 {
+	if s == PAL {
+		return 312
+	}
+	return 262
+}
+
+// ClocksPerFrame returns the master clocks in a non-interlaced frame
+func (s Standard) ClocksPerFrame() uint64 {
+	return uint64(s.LinesPerFrame()) * ClocksPerLine
+}
+
+// VBlankLine returns the first line of vertical blank, which is later when SETINI ($2133) selects overscan
+func (s Standard) VBlankLine(overscan bool) uint16 {
+	if overscan {
+		return 240
+	}
+	return 225
+}
+
+// Frame returns the duration of a frame
+func (s Standard) Frame() time.Duration {
+	if s == PAL {
+		return PALFrame
+	}
+	return Frame
+}